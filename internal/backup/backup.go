@@ -0,0 +1,206 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Store keeps copies of binaries from before they were updated.
+//
+// Each backup is stored in
+// `<dir>/<binary name>/<source key>/<version>/<binary name>`, where the source
+// key identifies the directory the binary is installed in. Binaries with the
+// same name in different directories (for example a cross-compiled copy in
+// a `<goos>_<goarch>` subdirectory) have separate backups.
+//
+// Only the newest generations of backups of each binary are kept.
+type Store struct {
+	dir         string
+	generations int
+}
+
+// Entry is a single backup of a binary.
+type Entry struct {
+	Name    string
+	Version string
+	// Path is the path to the backed up binary.
+	Path string
+	// Time is when the backup was made.
+	Time time.Time
+}
+
+func NewStore(dir string, generations int) Store {
+	return Store{
+		dir:         dir,
+		generations: generations,
+	}
+}
+
+// DefaultDir returns the default backup directory.
+//
+// It is `$XDG_DATA_HOME/go-global-update/backups`, falling back to
+// `~/.local/share/go-global-update/backups`.
+func DefaultDir() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("could not determine the backup directory: %w", err)
+		}
+		dataHome = filepath.Join(homeDir, ".local", "share")
+	}
+
+	return filepath.Join(dataHome, "go-global-update", "backups"), nil
+}
+
+// Backup copies the binary to the store and removes backups older than the
+// kept generations.
+func (s *Store) Backup(binaryPath, name, version string) (Entry, error) {
+	backupPath := filepath.Join(s.binaryDir(binaryPath, name), version, name)
+	if err := os.MkdirAll(filepath.Dir(backupPath), 0o755); err != nil {
+		return Entry{}, fmt.Errorf("could not create backup directory: %w", err)
+	}
+	if err := copyFile(binaryPath, backupPath); err != nil {
+		return Entry{}, fmt.Errorf("could not back up %s: %w", binaryPath, err)
+	}
+
+	if err := s.prune(binaryPath, name); err != nil {
+		return Entry{}, err
+	}
+
+	return Entry{Name: name, Version: version, Path: backupPath, Time: time.Now()}, nil
+}
+
+// List returns the backups of the binary at binaryPath, newest first.
+func (s *Store) List(binaryPath, name string) ([]Entry, error) {
+	binaryDir := s.binaryDir(binaryPath, name)
+	versionDirs, err := os.ReadDir(binaryDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not list backups of %s: %w", name, err)
+	}
+
+	var entries []Entry
+	for _, versionDir := range versionDirs {
+		backupPath := filepath.Join(binaryDir, versionDir.Name(), name)
+		info, err := os.Stat(backupPath)
+		if err != nil {
+			continue
+		}
+
+		entries = append(entries, Entry{
+			Name:    name,
+			Version: versionDir.Name(),
+			Path:    backupPath,
+			Time:    info.ModTime(),
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.After(entries[j].Time)
+	})
+
+	return entries, nil
+}
+
+// Find returns the backup of the binary at binaryPath at the version. If the
+// version is empty, the newest backup is returned.
+func (s *Store) Find(binaryPath, name, version string) (Entry, error) {
+	entries, err := s.List(binaryPath, name)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	for _, entry := range entries {
+		if version == "" || entry.Version == version {
+			return entry, nil
+		}
+	}
+
+	if version == "" {
+		return Entry{}, fmt.Errorf("there are no backups of %s", name)
+	}
+
+	return Entry{}, fmt.Errorf("there is no backup of %s at version %s", name, version)
+}
+
+// Restore replaces the binary at targetPath with the backup.
+func (s *Store) Restore(entry Entry, targetPath string) error {
+	// NOTE: copy to a temporary file next to the target first, so the binary
+	// is replaced atomically.
+	temporaryPath := fmt.Sprintf("%s.restore-%d", targetPath, os.Getpid())
+	if err := copyFile(entry.Path, temporaryPath); err != nil {
+		return fmt.Errorf("could not restore %s: %w", entry.Name, err)
+	}
+	if err := os.Rename(temporaryPath, targetPath); err != nil {
+		_ = os.Remove(temporaryPath)
+		return fmt.Errorf("could not restore %s: %w", entry.Name, err)
+	}
+
+	return nil
+}
+
+func (s *Store) prune(binaryPath, name string) error {
+	entries, err := s.List(binaryPath, name)
+	if err != nil {
+		return err
+	}
+	if len(entries) <= s.generations {
+		return nil
+	}
+
+	for _, entry := range entries[s.generations:] {
+		if err := os.RemoveAll(filepath.Dir(entry.Path)); err != nil {
+			return fmt.Errorf("could not remove old backup of %s at version %s: %w", name, entry.Version, err)
+		}
+	}
+
+	return nil
+}
+
+// binaryDir returns the directory with the backups of the binary at
+// binaryPath.
+func (s *Store) binaryDir(binaryPath, name string) string {
+	sourceDir := filepath.Dir(binaryPath)
+	if absoluteSourceDir, err := filepath.Abs(sourceDir); err == nil {
+		sourceDir = absoluteSourceDir
+	}
+	sourceKey := sha256.Sum256([]byte(sourceDir))
+
+	return filepath.Join(s.dir, name, hex.EncodeToString(sourceKey[:])[:12])
+}
+
+func copyFile(sourcePath, targetPath string) error {
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	info, err := source.Stat()
+	if err != nil {
+		return err
+	}
+
+	target, err := os.OpenFile(targetPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(target, source); err != nil {
+		target.Close()
+		return err
+	}
+
+	return target.Close()
+}
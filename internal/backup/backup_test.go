@@ -0,0 +1,104 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeBinary(t *testing.T, path, contents string) {
+	require.Nil(t, os.WriteFile(path, []byte(contents), 0o755))
+}
+
+func backupAt(t *testing.T, store *Store, binaryPath, version string, backupTime time.Time) {
+	entry, err := store.Backup(binaryPath, "tool", version)
+	require.Nil(t, err)
+	require.Nil(t, os.Chtimes(entry.Path, backupTime, backupTime))
+}
+
+func TestBackupKeepsGenerations(t *testing.T) {
+	binaryPath := filepath.Join(t.TempDir(), "tool")
+	store := NewStore(t.TempDir(), 2)
+	start := time.Now().Add(-time.Hour)
+
+	for i, version := range []string{"v1.0.0", "v1.1.0", "v1.2.0"} {
+		writeBinary(t, binaryPath, version)
+		backupAt(t, &store, binaryPath, version, start.Add(time.Duration(i)*time.Minute))
+	}
+	// NOTE: the generations are pruned when the next backup is made
+	writeBinary(t, binaryPath, "v1.3.0")
+	backupAt(t, &store, binaryPath, "v1.3.0", start.Add(3*time.Minute))
+
+	entries, err := store.List(binaryPath, "tool")
+	require.Nil(t, err)
+
+	var versions []string
+	for _, entry := range entries {
+		versions = append(versions, entry.Version)
+	}
+	assert.Equal(t, []string{"v1.3.0", "v1.2.0"}, versions)
+}
+
+func TestRestore(t *testing.T) {
+	binaryPath := filepath.Join(t.TempDir(), "tool")
+	store := NewStore(t.TempDir(), 3)
+	start := time.Now().Add(-time.Hour)
+
+	writeBinary(t, binaryPath, "v1.0.0")
+	backupAt(t, &store, binaryPath, "v1.0.0", start)
+	writeBinary(t, binaryPath, "v1.1.0")
+	backupAt(t, &store, binaryPath, "v1.1.0", start.Add(time.Minute))
+	writeBinary(t, binaryPath, "v1.2.0")
+
+	entry, err := store.Find(binaryPath, "tool", "")
+	require.Nil(t, err)
+	assert.Equal(t, "v1.1.0", entry.Version, "the newest backup should be found by default")
+
+	entry, err = store.Find(binaryPath, "tool", "v1.0.0")
+	require.Nil(t, err)
+	require.Nil(t, store.Restore(entry, binaryPath))
+
+	contents, err := os.ReadFile(binaryPath)
+	require.Nil(t, err)
+	assert.Equal(t, "v1.0.0", string(contents))
+
+	info, err := os.Stat(binaryPath)
+	require.Nil(t, err)
+	assert.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+
+	_, err = store.Find(binaryPath, "tool", "v0.9.0")
+	assert.NotNil(t, err)
+	_, err = store.Find(filepath.Join(filepath.Dir(binaryPath), "other-tool"), "other-tool", "")
+	assert.NotNil(t, err)
+}
+
+func TestSeparateBackupsOfBinariesInDifferentDirectories(t *testing.T) {
+	binDir := t.TempDir()
+	binaryPath := filepath.Join(binDir, "tool")
+	crossCompiledBinaryPath := filepath.Join(binDir, "linux_arm64", "tool")
+	require.Nil(t, os.Mkdir(filepath.Dir(crossCompiledBinaryPath), 0o755))
+	store := NewStore(t.TempDir(), 3)
+
+	writeBinary(t, binaryPath, "host")
+	writeBinary(t, crossCompiledBinaryPath, "linux/arm64")
+	_, err := store.Backup(binaryPath, "tool", "v1.0.0")
+	require.Nil(t, err)
+	_, err = store.Backup(crossCompiledBinaryPath, "tool", "v1.0.0")
+	require.Nil(t, err)
+
+	for path, expectedContents := range map[string]string{
+		binaryPath:              "host",
+		crossCompiledBinaryPath: "linux/arm64",
+	} {
+		entry, err := store.Find(path, "tool", "v1.0.0")
+		require.Nil(t, err)
+
+		contents, err := os.ReadFile(entry.Path)
+		require.Nil(t, err)
+		assert.Equal(t, expectedContents, string(contents))
+	}
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the declarative configuration read from the config file.
+type Config struct {
+	// Dirs are additional directories with go binaries, for example
+	// `~/.local/bin`. A leading `~` is expanded to the home directory.
+	Dirs []string `toml:"dirs" yaml:"dirs"`
+	// Binaries contains per-binary settings. The keys are binary names.
+	Binaries map[string]Binary `toml:"binaries" yaml:"binaries"`
+}
+
+// Binary contains the settings of a single binary.
+type Binary struct {
+	// Hold prevents the binary from being updated.
+	Hold bool `toml:"hold" yaml:"hold"`
+	// Version pins the binary to a version or a module version query, for
+	// example `v1.2.3`, `v1.2` (the latest `v1.2.x`), or `<v2.0.0`.
+	//
+	// See https://go.dev/ref/mod#version-queries
+	Version string `toml:"version" yaml:"version"`
+	// BuildTags are used in addition to the build tags the binary was built
+	// with.
+	BuildTags []string `toml:"build_tags" yaml:"build_tags"`
+	// Env are additional environment variables set when installing the
+	// binary.
+	Env map[string]string `toml:"env" yaml:"env"`
+	// PathURL is an alternate package path to install the binary from, for
+	// example when the package moved to a different module.
+	PathURL string `toml:"path" yaml:"path"`
+	// Check are the arguments the binary is run with after it is updated, for
+	// example `["--version"]`. If the binary fails, the update is rolled back.
+	Check []string `toml:"check" yaml:"check"`
+	// Symlinks determines how the binary is updated if it is a symlink:
+	// `target` updates the file the symlink points to, `skip` does not update
+	// it, and `retarget` installs a new versioned file and points the symlink
+	// to it. It overrides the `--symlinks` flag.
+	Symlinks string `toml:"symlinks" yaml:"symlinks"`
+}
+
+var symlinkModes = []string{"target", "skip", "retarget"}
+
+// EnvList returns the environment variables in the `KEY=value` form, sorted
+// by key.
+func (b *Binary) EnvList() []string {
+	var env []string
+	for key, value := range b.Env {
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(env)
+
+	return env
+}
+
+var configFileNames = []string{"config.toml", "config.yaml", "config.yml"}
+
+// DefaultDir returns the directory with the config file.
+//
+// It is `$XDG_CONFIG_HOME/go-global-update`, falling back to the user's
+// default config directory.
+func DefaultDir() (string, error) {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		var err error
+		configHome, err = os.UserConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("could not determine the config directory: %w", err)
+		}
+	}
+
+	return filepath.Join(configHome, "go-global-update"), nil
+}
+
+// LoadDefault loads the config file from the default directory.
+//
+// A missing config file is not an error. An empty Config is returned instead.
+func LoadDefault() (Config, error) {
+	dir, err := DefaultDir()
+	if err != nil {
+		return Config{}, err
+	}
+
+	for _, name := range configFileNames {
+		path := filepath.Join(dir, name)
+		config, err := Load(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		return config, err
+	}
+
+	return Config{}, nil
+}
+
+// Load loads the config file. The format is determined based on the file
+// extension.
+func Load(path string) (Config, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("could not read config file: %w", err)
+	}
+
+	var config Config
+	switch extension := strings.ToLower(filepath.Ext(path)); extension {
+	case ".toml":
+		var metadata toml.MetaData
+		metadata, err = toml.NewDecoder(bytes.NewReader(contents)).Decode(&config)
+		if undecodedKeys := metadata.Undecoded(); err == nil && len(undecodedKeys) > 0 {
+			err = fmt.Errorf("unknown keys: %v", undecodedKeys)
+		}
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(contents))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&config)
+		if errors.Is(err, io.EOF) {
+			// NOTE: an empty YAML document
+			err = nil
+		}
+	default:
+		return Config{}, fmt.Errorf("unsupported config file extension %q (expected .toml, .yaml, or .yml)", extension)
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("could not parse config file %s: %w", path, err)
+	}
+
+	for name, binary := range config.Binaries {
+		if binary.Symlinks != "" && !containsString(symlinkModes, binary.Symlinks) {
+			return Config{}, fmt.Errorf("invalid symlinks setting %q of %s in config file %s (expected one of %v)",
+				binary.Symlinks, name, path, symlinkModes)
+		}
+	}
+
+	for i, dir := range config.Dirs {
+		config.Dirs[i], err = expandHome(dir)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	return config, nil
+}
+
+// expandHome replaces a leading `~` in the path with the home directory.
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not expand %s: %w", path, err)
+	}
+
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
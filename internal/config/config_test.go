@@ -0,0 +1,146 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, dir, name, contents string) string {
+	path := filepath.Join(dir, name)
+	require.Nil(t, os.WriteFile(path, []byte(contents), 0o644))
+
+	return path
+}
+
+var expectedConfig = Config{
+	Binaries: map[string]Binary{
+		"gopls": {
+			Version: "v0.14",
+			Check:   []string{"version"},
+		},
+		"golangci-lint": {
+			Hold: true,
+		},
+		"shfmt": {
+			BuildTags: []string{"netgo"},
+			Env:       map[string]string{"CGO_ENABLED": "0"},
+		},
+		"cobra": {
+			PathURL: "github.com/spf13/cobra-cli",
+		},
+	},
+}
+
+func TestLoadTOML(t *testing.T) {
+	path := writeConfigFile(t, t.TempDir(), "config.toml", `
+[binaries.gopls]
+version = "v0.14"
+check = ["version"]
+
+[binaries.golangci-lint]
+hold = true
+
+[binaries.shfmt]
+build_tags = ["netgo"]
+env = { CGO_ENABLED = "0" }
+
+[binaries.cobra]
+path = "github.com/spf13/cobra-cli"
+`)
+
+	config, err := Load(path)
+	require.Nil(t, err)
+	assert.Equal(t, expectedConfig, config)
+}
+
+func TestLoadYAML(t *testing.T) {
+	path := writeConfigFile(t, t.TempDir(), "config.yaml", `
+binaries:
+  gopls:
+    version: v0.14
+    check: [version]
+  golangci-lint:
+    hold: true
+  shfmt:
+    build_tags: [netgo]
+    env:
+      CGO_ENABLED: "0"
+  cobra:
+    path: github.com/spf13/cobra-cli
+`)
+
+	config, err := Load(path)
+	require.Nil(t, err)
+	assert.Equal(t, expectedConfig, config)
+}
+
+func TestLoadUnknownKeys(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, path := range []string{
+		writeConfigFile(t, dir, "config.toml", `
+[binaries.gopls]
+verison = "v0.14"
+`),
+		writeConfigFile(t, dir, "config.yaml", `
+binaries:
+  gopls:
+    verison: v0.14
+`),
+	} {
+		_, err := Load(path)
+		assert.NotNil(t, err, "expected an error for an unknown key in %s", path)
+	}
+}
+
+func TestLoadInvalidSymlinks(t *testing.T) {
+	path := writeConfigFile(t, t.TempDir(), "config.toml", `
+[binaries.gopls]
+symlinks = "follow"
+`)
+
+	_, err := Load(path)
+	assert.NotNil(t, err)
+}
+
+func TestLoadDefault(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	config, err := LoadDefault()
+	require.Nil(t, err, "a missing config file should not be an error")
+	assert.Equal(t, Config{}, config)
+
+	require.Nil(t, os.Mkdir(filepath.Join(configHome, "go-global-update"), 0o755))
+	writeConfigFile(t, filepath.Join(configHome, "go-global-update"), "config.yaml", `
+binaries:
+  golangci-lint:
+    hold: true
+`)
+
+	config, err = LoadDefault()
+	require.Nil(t, err)
+	assert.Equal(t, Config{Binaries: map[string]Binary{"golangci-lint": {Hold: true}}}, config)
+}
+
+func TestExpandHomeInDirs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	path := writeConfigFile(t, t.TempDir(), "config.toml", `
+dirs = ["~/.local/bin", "/opt/tools/bin"]
+`)
+
+	config, err := Load(path)
+	require.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(home, ".local", "bin"), "/opt/tools/bin"}, config.Dirs)
+}
+
+func TestEnvList(t *testing.T) {
+	binary := Binary{Env: map[string]string{"GOFLAGS": "-mod=mod", "CGO_ENABLED": "0"}}
+
+	assert.Equal(t, []string{"CGO_ENABLED=0", "GOFLAGS=-mod=mod"}, binary.EnvList())
+}
@@ -0,0 +1,165 @@
+package gobinaries
+
+import (
+	"context"
+	"debug/buildinfo"
+	"errors"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/Gelio/go-global-update/internal/gocli"
+)
+
+var (
+	// ErrNotGoBinary means the file was not built by the go toolchain, for
+	// example a shell script.
+	ErrNotGoBinary = errors.New("not a Go binary")
+	// ErrDirectory means the path is a directory, not a binary.
+	ErrDirectory = errors.New("is a directory")
+	// ErrNoBuildInfo means the binary was built by the go toolchain, but it
+	// does not contain module information, for example because it was built
+	// in GOPATH mode or its build information was stripped.
+	ErrNoBuildInfo = errors.New("no build info")
+)
+
+// BuildInfoReader reads the build information embedded in a Go binary.
+type BuildInfoReader interface {
+	ReadBuildInfo(ctx context.Context, binaryPath string) (*debug.BuildInfo, error)
+}
+
+// FileBuildInfoReader reads the build information straight from the binary
+// file, without spawning any processes.
+type FileBuildInfoReader struct{}
+
+func (*FileBuildInfoReader) ReadBuildInfo(_ context.Context, binaryPath string) (*debug.BuildInfo, error) {
+	buildInfo, err := buildinfo.ReadFile(binaryPath)
+	if err != nil {
+		return nil, classifyReadError(binaryPath, err, err.Error())
+	}
+
+	return buildInfo, nil
+}
+
+// GoVersionBuildInfoReader reads the build information by parsing the output
+// of `go version -m`.
+type GoVersionBuildInfoReader struct {
+	cmdRunner gocli.GoCmdRunner
+}
+
+func NewGoVersionBuildInfoReader(cmdRunner gocli.GoCmdRunner) GoVersionBuildInfoReader {
+	return GoVersionBuildInfoReader{
+		cmdRunner,
+	}
+}
+
+func (r *GoVersionBuildInfoReader) ReadBuildInfo(ctx context.Context, binaryPath string) (*debug.BuildInfo, error) {
+	// NOTE: `go version -m` reports all binaries in a directory instead of
+	// failing
+	if fileInfo, err := os.Stat(binaryPath); err == nil && fileInfo.IsDir() {
+		return nil, ErrDirectory
+	}
+
+	moduleOutput, err := r.cmdRunner.RunGoCommand(ctx, "version", "-m", binaryPath)
+	if err != nil {
+		err = fmt.Errorf("could not retrieve version information about binary %s: %w\n%v", binaryPath, err, moduleOutput)
+		return nil, classifyReadError(binaryPath, err, moduleOutput)
+	}
+
+	return parseGoVersionOutput(moduleOutput), nil
+}
+
+// classifyReadError wraps err with ErrNotGoBinary, ErrDirectory, or
+// os.ErrPermission if the message shows the binary could not be read for one
+// of these reasons.
+func classifyReadError(binaryPath string, err error, message string) error {
+	if fileInfo, statErr := os.Stat(binaryPath); statErr == nil && fileInfo.IsDir() {
+		return ErrDirectory
+	}
+
+	// NOTE: debug/buildinfo does not export its errors, and `go version -m`
+	// only prints them
+	switch {
+	case errors.Is(err, os.ErrPermission):
+		return err
+	case strings.Contains(message, "permission denied"):
+		return fmt.Errorf("%w: %v", os.ErrPermission, err)
+	case strings.Contains(message, "unrecognized file format"), strings.Contains(message, "not a Go executable"):
+		return fmt.Errorf("%w: %v", ErrNotGoBinary, err)
+	default:
+		return err
+	}
+}
+
+// parseGoVersionOutput parses the output of `go version -m`.
+//
+// The output is similar to the format of debug.BuildInfo.String(), but it is
+// preceded by a `binaryPath: goVersion` line and the columns may be separated
+// by any whitespace.
+func parseGoVersionOutput(output string) *debug.BuildInfo {
+	buildInfo := debug.BuildInfo{}
+	// lastModule is the module that a `=>` (replace) line refers to.
+	var lastModule *debug.Module
+	parsedHeader := false
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if !parsedHeader {
+			parsedHeader = true
+			if separatorIndex := strings.LastIndex(line, ": "); separatorIndex != -1 {
+				buildInfo.GoVersion = strings.TrimSpace(line[separatorIndex+2:])
+				continue
+			}
+		}
+
+		switch fields[0] {
+		case "path":
+			if len(fields) > 1 {
+				buildInfo.Path = fields[1]
+			}
+		case "mod":
+			buildInfo.Main = parseModuleFields(fields[1:])
+			lastModule = &buildInfo.Main
+		case "dep":
+			dep := parseModuleFields(fields[1:])
+			buildInfo.Deps = append(buildInfo.Deps, &dep)
+			lastModule = &dep
+		case "=>":
+			if lastModule != nil {
+				replacement := parseModuleFields(fields[1:])
+				lastModule.Replace = &replacement
+			}
+		case "build":
+			setting := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "build"))
+			key, value, _ := strings.Cut(setting, "=")
+			// NOTE: values containing whitespace or quotes are quoted
+			if unquotedValue, err := strconv.Unquote(value); err == nil {
+				value = unquotedValue
+			}
+			buildInfo.Settings = append(buildInfo.Settings, debug.BuildSetting{Key: key, Value: value})
+		}
+	}
+
+	return &buildInfo
+}
+
+func parseModuleFields(fields []string) debug.Module {
+	var module debug.Module
+	if len(fields) > 0 {
+		module.Path = fields[0]
+	}
+	if len(fields) > 1 {
+		module.Version = fields[1]
+	}
+	if len(fields) > 2 {
+		module.Sum = fields[2]
+	}
+
+	return module
+}
@@ -0,0 +1,84 @@
+package gobinaries_test
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"testing"
+
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+	"github.com/Gelio/go-global-update/internal/goclitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileBuildInfoReader(t *testing.T) {
+	// NOTE: the test binary is a Go binary built in module mode, so it contains
+	// build information.
+	testBinaryPath, err := os.Executable()
+	require.Nil(t, err)
+
+	reader := gobinaries.FileBuildInfoReader{}
+	buildInfo, err := reader.ReadBuildInfo(context.Background(), testBinaryPath)
+	require.Nil(t, err)
+
+	assert.Equal(t, "github.com/Gelio/go-global-update", buildInfo.Main.Path)
+	assert.Equal(t, runtime.Version(), buildInfo.GoVersion)
+}
+
+func TestFileBuildInfoReaderNotAGoBinary(t *testing.T) {
+	reader := gobinaries.FileBuildInfoReader{}
+	_, err := reader.ReadBuildInfo(context.Background(), "buildinfo_reader_test.go")
+	assert.ErrorIs(t, err, gobinaries.ErrNotGoBinary)
+	assert.Equal(t, gobinaries.IntrospectionErrorNotGoBinary, gobinaries.ClassifyIntrospectionError(err))
+}
+
+func TestBuildInfoReadersDirectory(t *testing.T) {
+	dir := t.TempDir()
+	fileReader := gobinaries.FileBuildInfoReader{}
+	goVersionReader := gobinaries.NewGoVersionBuildInfoReader(&goclitest.TestGoCmdRunner{})
+
+	_, err := fileReader.ReadBuildInfo(context.Background(), dir)
+	assert.ErrorIs(t, err, gobinaries.ErrDirectory)
+	_, err = goVersionReader.ReadBuildInfo(context.Background(), dir)
+	assert.ErrorIs(t, err, gobinaries.ErrDirectory)
+}
+
+func TestGoVersionBuildInfoReaderReplaceAndQuotedSettings(t *testing.T) {
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			goclitest.GetModuleInfoMockResponse("/home/test/go/bin", "tool", `
+/home/test/go/bin/tool: go1.22.0
+	path	example.com/tool/cmd/tool
+	mod	example.com/tool	v1.2.3	h1:abc=
+	dep	example.com/dep	v0.1.0	h1:def=
+	=>	example.com/fork	v0.1.1	h1:ghi=
+	build	-ldflags="-s -w"
+	build	CGO_ENABLED=0
+`),
+		},
+	}
+	reader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+
+	buildInfo, err := reader.ReadBuildInfo(context.Background(), "/home/test/go/bin/tool")
+	require.Nil(t, err)
+
+	assert.Equal(t, &debug.BuildInfo{
+		GoVersion: "go1.22.0",
+		Path:      "example.com/tool/cmd/tool",
+		Main:      debug.Module{Path: "example.com/tool", Version: "v1.2.3", Sum: "h1:abc="},
+		Deps: []*debug.Module{
+			{
+				Path:    "example.com/dep",
+				Version: "v0.1.0",
+				Sum:     "h1:def=",
+				Replace: &debug.Module{Path: "example.com/fork", Version: "v0.1.1", Sum: "h1:ghi="},
+			},
+		},
+		Settings: []debug.BuildSetting{
+			{Key: "-ldflags", Value: "-s -w"},
+			{Key: "CGO_ENABLED", Value: "0"},
+		},
+	}, buildInfo)
+}
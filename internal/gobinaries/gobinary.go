@@ -1,5 +1,14 @@
 package gobinaries
 
+import (
+	"path"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
 type GoBinary struct {
 	// ModuleURL is the `mod` URL from `go version -m`
 	ModuleURL string
@@ -7,7 +16,10 @@ type GoBinary struct {
 	PathURL string
 	Name    string
 	// Path is the filesystem path to this binary.
-	Path          string
+	Path string
+	// SymlinkTarget is the resolved path of the file that Path links to. It is
+	// empty if Path is not a symlink.
+	SymlinkTarget string
 	Version       string
 	LatestVersion string
 
@@ -16,10 +28,180 @@ type GoBinary struct {
 	//
 	// When updating a binary, the same build tags should be used.
 	BuildTags []string
+
+	// GoVersion is the version of the go toolchain that built the binary.
+	GoVersion string
+	// Deps are the module dependencies that were compiled into the binary.
+	Deps []*debug.Module
+	// Settings are the build settings (for example `-tags` or `CGO_ENABLED`)
+	// that were used to build the binary.
+	Settings []debug.BuildSetting
+
+	// Held binaries should not be updated.
+	Held bool
+	// VersionQuery is the module version query used instead of `latest` to
+	// resolve LatestVersion (for example `v1.2.3`, `v1.2`, or `<v2.0.0`).
+	VersionQuery string
+	// VersionRequested is set when VersionQuery was requested explicitly (on
+	// the command line or in the config file). The binary is installed at the resolved
+	// version even if that is a downgrade.
+	VersionRequested bool
+	// InstallPathURL is the package path to install the binary from when it
+	// differs from PathURL (for example, when the package moved to a
+	// different module).
+	InstallPathURL string
+	// InstallModuleURL is the module containing InstallPathURL.
+	InstallModuleURL string
+
+	// NewMajorModuleURL is the module path of the newest major version of the
+	// module (for example `mvdan.cc/sh/v4` for `mvdan.cc/sh/v3`). It is empty
+	// if there is no newer major version.
+	NewMajorModuleURL string
+	// NewMajorVersion is the latest version of NewMajorModuleURL.
+	NewMajorVersion string
 }
 
+// UpgradeState describes how the version of a binary relates to its latest
+// version.
+type UpgradeState string
+
+const (
+	// UpgradeStateNewerThanLatest means the binary is newer than the latest
+	// version, for example when it was installed at a prerelease or
+	// a pseudo-version.
+	UpgradeStateNewerThanLatest UpgradeState = "newer-than-latest"
+	UpgradeStateEqual           UpgradeState = "equal"
+	UpgradeStatePatchBehind     UpgradeState = "patch-behind"
+	UpgradeStateMinorBehind     UpgradeState = "minor-behind"
+	UpgradeStateMajorBehind     UpgradeState = "major-behind"
+	// UpgradeStateIncomparable means either version is not a valid semantic
+	// version, for example when the binary was built from source or the
+	// latest version is unknown.
+	UpgradeStateIncomparable UpgradeState = "incomparable"
+)
+
+// UpgradeState compares Version with LatestVersion.
+func (b *GoBinary) UpgradeState() UpgradeState {
+	if !semver.IsValid(b.Version) || !semver.IsValid(b.LatestVersion) {
+		return UpgradeStateIncomparable
+	}
+
+	switch {
+	case semver.Compare(b.Version, b.LatestVersion) > 0:
+		return UpgradeStateNewerThanLatest
+	case semver.Compare(b.Version, b.LatestVersion) == 0:
+		return UpgradeStateEqual
+	case semver.Major(b.Version) != semver.Major(b.LatestVersion):
+		return UpgradeStateMajorBehind
+	case semver.MajorMinor(b.Version) != semver.MajorMinor(b.LatestVersion):
+		return UpgradeStateMinorBehind
+	default:
+		return UpgradeStatePatchBehind
+	}
+}
+
+// UpgradePossible determines whether installing LatestVersion would upgrade
+// the binary.
+//
+// Binaries newer than LatestVersion are not upgradable. Installing
+// LatestVersion would downgrade them.
 func (b *GoBinary) UpgradePossible() bool {
-	return b.Version != b.LatestVersion
+	if b.PathChanged() {
+		return true
+	}
+
+	switch b.UpgradeState() {
+	case UpgradeStatePatchBehind, UpgradeStateMinorBehind, UpgradeStateMajorBehind:
+		return true
+	case UpgradeStateIncomparable:
+		return b.Version != b.LatestVersion
+	default:
+		return false
+	}
+}
+
+// DowngradePossible determines whether installing LatestVersion would
+// downgrade the binary.
+func (b *GoBinary) DowngradePossible() bool {
+	return !b.PathChanged() && b.UpgradeState() == UpgradeStateNewerThanLatest
+}
+
+// PathChanged determines whether the binary should be installed from a
+// different package than the one it was built from.
+func (b *GoBinary) PathChanged() bool {
+	return b.InstallPathURL != "" && b.InstallPathURL != b.PathURL
+}
+
+// InstallPath returns the package path to install the binary from.
+func (b *GoBinary) InstallPath() string {
+	if b.PathChanged() {
+		return b.InstallPathURL
+	}
+
+	return b.PathURL
+}
+
+// NewMajorPathURL returns the package path of the binary in the newest major
+// version of the module.
+func (b *GoBinary) NewMajorPathURL() string {
+	if b.NewMajorModuleURL == "" {
+		return ""
+	}
+
+	return b.NewMajorModuleURL + strings.TrimPrefix(b.InstallPath(), b.InstallModule())
+}
+
+// UseNewMajorVersion makes the binary be installed from the newest major
+// version of the module.
+func (b *GoBinary) UseNewMajorVersion() {
+	if b.NewMajorModuleURL == "" {
+		return
+	}
+
+	b.InstallPathURL = b.NewMajorPathURL()
+	b.InstallModuleURL = b.NewMajorModuleURL
+	b.LatestVersion = b.NewMajorVersion
+	b.NewMajorModuleURL = ""
+	b.NewMajorVersion = ""
+}
+
+// InstallModule returns the module containing InstallPath.
+func (b *GoBinary) InstallModule() string {
+	if b.PathChanged() {
+		return b.InstallModuleURL
+	}
+
+	return b.ModuleURL
+}
+
+// versionQuery returns the module version query used to resolve
+// LatestVersion.
+func (b *GoBinary) versionQuery() string {
+	if b.VersionQuery != "" {
+		return b.VersionQuery
+	}
+
+	return "latest"
+}
+
+// Setting returns the value of the build setting, or an empty string if the
+// setting is missing.
+func (b *GoBinary) Setting(key string) string {
+	for _, setting := range b.Settings {
+		if setting.Key == key {
+			return setting.Value
+		}
+	}
+
+	return ""
+}
+
+// CrossCompiled determines whether the binary was installed for a different
+// platform than the host. Such binaries are kept in a `<goos>_<goarch>`
+// subdirectory of GOPATH/bin.
+func (b *GoBinary) CrossCompiled() bool {
+	goos, goarch := b.Setting("GOOS"), b.Setting("GOARCH")
+	return goos != "" && goarch != "" && filepath.Base(filepath.Dir(b.Path)) == goos+"_"+goarch
 }
 
 // BuiltFromSource determines whether the binary was built or installed from source.
@@ -32,3 +214,41 @@ func (b *GoBinary) BuiltWithGoBuild() bool {
 	// See https://github.com/Gelio/go-global-update/issues/3#issuecomment-1071566068
 	return b.PathURL == "command-line-arguments"
 }
+
+// canResolveLatestVersion determines whether the latest version of the
+// binary's module can be looked up.
+func (b *GoBinary) canResolveLatestVersion() bool {
+	// NOTE: module URL may be missing on go 1.18 for binaries built using `go build`
+	// In case the package is built from source (path is
+	// "command-line-arguments"), behave consistently on all go versions
+	return b.ModuleURL != "" && !b.BuiltWithGoBuild()
+}
+
+// ExecName returns the name of the executable that `go install` creates for
+// the package, without GOEXE. It is the last element of the package path, or
+// the one before it if the last element is a major version suffix (for
+// example `shfmt` for `mvdan.cc/sh/v3/cmd/shfmt` and `foo` for
+// `example.com/foo/v2`).
+func ExecName(pathURL string) string {
+	elem := path.Base(pathURL)
+	if elem != pathURL && isVersionElement(elem) {
+		elem = path.Base(path.Dir(pathURL))
+	}
+
+	return elem
+}
+
+// isVersionElement determines whether the path element is a major version
+// suffix (`v2`, `v3`, etc.).
+func isVersionElement(elem string) bool {
+	if len(elem) < 2 || elem[0] != 'v' || elem[1] == '0' || elem == "v1" {
+		return false
+	}
+	for _, r := range elem[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,81 @@
+package gobinaries_test
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpgradeState(t *testing.T) {
+	testCases := []struct {
+		version           string
+		latestVersion     string
+		upgradeState      gobinaries.UpgradeState
+		upgradePossible   bool
+		downgradePossible bool
+	}{
+		{"v1.2.3", "v1.2.3", gobinaries.UpgradeStateEqual, false, false},
+		{"v1.2.3", "v1.2.4", gobinaries.UpgradeStatePatchBehind, true, false},
+		{"v1.2.3-rc.1", "v1.2.3", gobinaries.UpgradeStatePatchBehind, true, false},
+		{"v1.2.3", "v1.3.0", gobinaries.UpgradeStateMinorBehind, true, false},
+		{"v0.9.0", "v1.0.0", gobinaries.UpgradeStateMajorBehind, true, false},
+		{"v1.3.0-rc.1", "v1.2.3", gobinaries.UpgradeStateNewerThanLatest, false, true},
+		{"v1.2.4-0.20220101000000-abcdefabcdef", "v1.2.3", gobinaries.UpgradeStateNewerThanLatest, false, true},
+		{"(devel)", "v1.2.3", gobinaries.UpgradeStateIncomparable, true, false},
+		{"v1.2.3", "", gobinaries.UpgradeStateIncomparable, true, false},
+	}
+
+	for _, testCase := range testCases {
+		binary := gobinaries.GoBinary{Version: testCase.version, LatestVersion: testCase.latestVersion}
+
+		assert.Equal(t, testCase.upgradeState, binary.UpgradeState(), "%s -> %s", testCase.version, testCase.latestVersion)
+		assert.Equal(t, testCase.upgradePossible, binary.UpgradePossible(), "%s -> %s", testCase.version, testCase.latestVersion)
+		assert.Equal(t, testCase.downgradePossible, binary.DowngradePossible(), "%s -> %s", testCase.version, testCase.latestVersion)
+	}
+}
+
+func TestCrossCompiled(t *testing.T) {
+	settings := []debug.BuildSetting{
+		{Key: "GOOS", Value: "linux"},
+		{Key: "GOARCH", Value: "arm64"},
+	}
+	testCases := []struct {
+		path          string
+		crossCompiled bool
+	}{
+		{"/home/test/go/bin/linux_arm64/shfmt", true},
+		{"/home/test/go/bin/shfmt", false},
+		{"/home/test/go/bin/linux_amd64/shfmt", false},
+	}
+
+	for _, testCase := range testCases {
+		binary := gobinaries.GoBinary{Path: testCase.path, Settings: settings}
+
+		assert.Equal(t, testCase.crossCompiled, binary.CrossCompiled(), testCase.path)
+	}
+}
+
+func TestBinaryNameOfCrossCompiledBinaries(t *testing.T) {
+	assert.Equal(t, "shfmt", gobinaries.BinaryName("/home/test/go/bin/windows_amd64/shfmt.exe", ""))
+	assert.Equal(t, "shfmt", gobinaries.BinaryName("/home/test/go/bin/linux_arm64/shfmt", ".exe"))
+	assert.Equal(t, "shfmt.exe", gobinaries.BinaryName("/home/test/go/bin/not_a_platform/shfmt.exe", ""))
+}
+
+func TestExecName(t *testing.T) {
+	testCases := []struct {
+		pathURL  string
+		execName string
+	}{
+		{"github.com/golangci/golangci-lint/cmd/golangci-lint", "golangci-lint"},
+		{"mvdan.cc/sh/v3/cmd/shfmt", "shfmt"},
+		{"example.com/foo/v2", "foo"},
+		{"example.com/foo/v1", "v1"},
+		{"v2", "v2"},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.execName, gobinaries.ExecName(testCase.pathURL), testCase.pathURL)
+	}
+}
@@ -1,8 +1,10 @@
 package gobinaries
 
 import (
+	"context"
 	"fmt"
 	"runtime"
+	"strings"
 	"sync"
 )
 
@@ -11,13 +13,24 @@ type IntrospectionResult struct {
 	Error  error
 }
 
-func IntrospectBinaries(introspecter *Introspecter, binaryNames []string) []IntrospectionResult {
-	results := make([]IntrospectionResult, len(binaryNames))
+// IntrospectBinaries reads the build information of the binaries and then
+// resolves the latest versions of their modules.
+func IntrospectBinaries(ctx context.Context, introspecter *Introspecter, binaryPaths []string) []IntrospectionResult {
+	results := ReadBinaries(ctx, introspecter, binaryPaths)
+	resolveLatestVersions(ctx, introspecter, results)
+
+	return results
+}
+
+// ReadBinaries reads the build information of the binaries without resolving
+// the latest versions of their modules. It does not need network access.
+func ReadBinaries(ctx context.Context, introspecter *Introspecter, binaryPaths []string) []IntrospectionResult {
+	results := make([]IntrospectionResult, len(binaryPaths))
 
 	var wg sync.WaitGroup
 	semaphore := make(chan struct{}, runtime.GOMAXPROCS(0))
-	for i, binaryName := range binaryNames {
-		i, binaryName := i, binaryName
+	for i, binaryPath := range binaryPaths {
+		i, binaryPath := i, binaryPath
 
 		wg.Add(1)
 		semaphore <- struct{}{}
@@ -25,9 +38,9 @@ func IntrospectBinaries(introspecter *Introspecter, binaryNames []string) []Intr
 			defer wg.Done()
 			defer func() { <-semaphore }()
 
-			binary, err := introspecter.Introspect(binaryName)
+			binary, err := introspecter.Introspect(ctx, binaryPath)
 			if err != nil {
-				err = fmt.Errorf("could not introspect binary %s: %w", binaryName, err)
+				err = fmt.Errorf("could not introspect binary %s: %w", binary.Name, err)
 			}
 
 			results[i] = IntrospectionResult{
@@ -40,3 +53,116 @@ func IntrospectBinaries(introspecter *Introspecter, binaryNames []string) []Intr
 
 	return results
 }
+
+type latestVersionResult struct {
+	version string
+	err     error
+
+	newMajorModuleURL string
+	newMajorVersion   string
+}
+
+// resolveLatestVersions looks up the latest version of each unique module
+// (and version query) among the introspected binaries.
+//
+// Multiple binaries are often installed from the same module (for example
+// goimports and stringer from golang.org/x/tools), so each module is only
+// queried once and the result is fanned out to all binaries from that module.
+// Newer major versions of the modules are discovered at the same time if the
+// introspecter is set up to find them.
+func resolveLatestVersions(ctx context.Context, introspecter *Introspecter, results []IntrospectionResult) {
+	resolveInstallModules(ctx, introspecter, results)
+
+	var moduleQueries []string
+	seenModuleQueries := make(map[string]bool)
+	for _, result := range results {
+		if result.Error != nil || !result.Binary.canResolveLatestVersion() {
+			continue
+		}
+
+		if moduleQuery := latestVersionModuleQuery(result.Binary); !seenModuleQueries[moduleQuery] {
+			seenModuleQueries[moduleQuery] = true
+			moduleQueries = append(moduleQueries, moduleQuery)
+		}
+	}
+
+	latestVersions := make([]latestVersionResult, len(moduleQueries))
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, runtime.GOMAXPROCS(0))
+	for i, moduleQuery := range moduleQueries {
+		i, moduleQuery := i, moduleQuery
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			moduleURL, query, _ := strings.Cut(moduleQuery, "@")
+			version, err := introspecter.getModuleVersion(ctx, moduleURL, query)
+			latestVersions[i] = latestVersionResult{version: version, err: err}
+
+			// NOTE: binaries pinned to a version query are not offered new
+			// major versions.
+			if err == nil && query == "latest" && introspecter.findNewMajorVersions {
+				latestVersions[i].newMajorModuleURL, latestVersions[i].newMajorVersion = introspecter.findNewMajorVersion(ctx, moduleURL)
+			}
+		}()
+	}
+	wg.Wait()
+
+	latestVersionsByModuleQuery := make(map[string]latestVersionResult, len(moduleQueries))
+	for i, moduleQuery := range moduleQueries {
+		latestVersionsByModuleQuery[moduleQuery] = latestVersions[i]
+	}
+
+	for i := range results {
+		result := &results[i]
+		if result.Error != nil || !result.Binary.canResolveLatestVersion() {
+			continue
+		}
+
+		moduleQuery := latestVersionModuleQuery(result.Binary)
+		latestVersion := latestVersionsByModuleQuery[moduleQuery]
+		if latestVersion.err != nil {
+			result.Error = fmt.Errorf("could not introspect binary %s: could not get latest version of %v: %w",
+				result.Binary.Name, moduleQuery, latestVersion.err)
+			continue
+		}
+
+		result.Binary.LatestVersion = latestVersion.version
+		result.Binary.NewMajorModuleURL = latestVersion.newMajorModuleURL
+		result.Binary.NewMajorVersion = latestVersion.newMajorVersion
+	}
+}
+
+func latestVersionModuleQuery(binary GoBinary) string {
+	return fmt.Sprintf("%s@%s", binary.InstallModule(), binary.versionQuery())
+}
+
+// resolveInstallModules finds the modules of binaries that should be
+// installed from a different package than they were built from.
+func resolveInstallModules(ctx context.Context, introspecter *Introspecter, results []IntrospectionResult) {
+	installModuleURLs := make(map[string]string)
+
+	for i := range results {
+		binary := &results[i].Binary
+		if results[i].Error != nil || !binary.PathChanged() || binary.InstallModuleURL != "" {
+			continue
+		}
+
+		moduleURL, ok := installModuleURLs[binary.InstallPathURL]
+		if !ok {
+			var err error
+			moduleURL, err = introspecter.findModuleURL(ctx, binary.InstallPathURL)
+			if err != nil {
+				results[i].Error = fmt.Errorf("could not introspect binary %s: %w", binary.Name, err)
+				continue
+			}
+			installModuleURLs[binary.InstallPathURL] = moduleURL
+		}
+
+		binary.InstallModuleURL = moduleURL
+	}
+}
@@ -1,147 +1,208 @@
 package gobinaries
 
 import (
+	"context"
 	"fmt"
+	"path"
 	"path/filepath"
-	"regexp"
+	"runtime/debug"
+	"strconv"
 	"strings"
 
 	"github.com/Gelio/go-global-update/internal/gocli"
 	"go.uber.org/zap"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
 )
 
 type Introspecter struct {
-	cmdRunner gocli.GoCmdRunner
-	gobin     string
-	logger    *zap.Logger
+	cmdRunner       gocli.GoCmdRunner
+	buildInfoReader BuildInfoReader
+	// goExe is the suffix of executables (GOEXE). It is not a part of binary
+	// names.
+	goExe  string
+	logger *zap.Logger
+	// overrides are keyed by binary names.
+	overrides map[string]Override
+	// findNewMajorVersions enables looking for new major versions of the
+	// modules when resolving the latest versions.
+	findNewMajorVersions bool
 }
 
-func NewIntrospecter(cmdRunner gocli.GoCmdRunner, gobin string, logger *zap.Logger) Introspecter {
+// Override changes which version of a binary is considered the latest one
+// and where the binary should be installed from.
+type Override struct {
+	// Hold marks the binary as held, so it should not be updated.
+	Hold bool
+	// VersionQuery is used instead of `latest` when resolving the latest
+	// version.
+	VersionQuery string
+	// VersionRequested marks VersionQuery as requested explicitly.
+	VersionRequested bool
+	// PathURL is the package path to install the binary from.
+	PathURL string
+}
+
+func NewIntrospecter(cmdRunner gocli.GoCmdRunner, buildInfoReader BuildInfoReader, goExe string, logger *zap.Logger) Introspecter {
 	return Introspecter{
 		cmdRunner,
-		gobin,
+		buildInfoReader,
+		goExe,
 		logger,
+		nil,
+		false,
 	}
 }
 
-func (i *Introspecter) Introspect(binaryName string) (GoBinary, error) {
-	binaryPath := filepath.Join(i.gobin, binaryName)
-	moduleInfo, err := i.getModuleInfo(binaryPath)
+// SetOverrides sets the overrides applied to introspected binaries. The keys
+// are binary names.
+func (i *Introspecter) SetOverrides(overrides map[string]Override) {
+	i.overrides = overrides
+}
+
+// SetFindNewMajorVersions enables looking for new major versions of the
+// modules. It takes an extra module proxy query per major version, so it
+// should only be enabled when the new major versions are shown or used.
+func (i *Introspecter) SetFindNewMajorVersions(find bool) {
+	i.findNewMajorVersions = find
+}
+
+func (i *Introspecter) Introspect(ctx context.Context, binaryPath string) (GoBinary, error) {
+	binaryName := BinaryName(binaryPath, i.goExe)
+	buildInfo, err := i.buildInfoReader.ReadBuildInfo(ctx, binaryPath)
 	if err != nil {
-		return GoBinary{}, fmt.Errorf("could not get module info about %v: %w", binaryPath, err)
+		return GoBinary{Name: binaryName, Path: binaryPath}, fmt.Errorf("could not get module info about %v: %w", binaryPath, err)
+	}
+	if buildInfo.Path == "" {
+		return GoBinary{Name: binaryName, Path: binaryPath}, fmt.Errorf("could not parse module information for binary %s: %w", binaryPath, ErrNoBuildInfo)
 	}
 
-	latestVersion := ""
-	// NOTE: module URL may be missing on go 1.18 for binaries built using `go build`
-	// In case the package is built from source (path is
-	// "command-line-arguments"), behave consistently on all go versions
-	if moduleInfo.moduleURL != "" && moduleInfo.pathURL != "command-line-arguments" {
-		latestVersion, err = i.getLatestModuleVersion(moduleInfo.moduleURL)
-		if err != nil {
-			return GoBinary{}, fmt.Errorf("could not get latest version of %v: %w", moduleInfo.moduleURL, err)
+	goBinary := newGoBinary(binaryName, binaryPath, buildInfo)
+	if override, ok := i.overrides[binaryName]; ok {
+		goBinary.Held = override.Hold
+		goBinary.VersionQuery = override.VersionQuery
+		goBinary.VersionRequested = override.VersionRequested
+		if override.PathURL != "" && override.PathURL != goBinary.PathURL {
+			goBinary.InstallPathURL = override.PathURL
 		}
 	}
-
-	goBinary := GoBinary{
-		ModuleURL:     moduleInfo.moduleURL,
-		PathURL:       moduleInfo.pathURL,
-		Version:       moduleInfo.version,
-		Name:          binaryName,
-		Path:          binaryPath,
-		LatestVersion: latestVersion,
-		BuildTags:     moduleInfo.buildTags,
+	if len(goBinary.BuildTags) > 0 {
+		i.logger.Sugar().Debugf("found build tags for binary %s: %v", binaryPath, goBinary.BuildTags)
+	} else {
+		i.logger.Sugar().Debugf("no build tags found for binary %s", binaryPath)
 	}
+
 	i.logger.Sugar().Debugf("introspected binary %s: %+v", binaryName, goBinary)
 
 	return goBinary, nil
 }
 
-type parsedGoModuleInfo struct {
-	moduleURL string
-	pathURL   string
-	version   string
-	buildTags []string
+// BinaryName returns the name of the binary at binaryPath, without the goExe
+// suffix.
+func BinaryName(binaryPath, goExe string) string {
+	// NOTE: cross-compiled binaries have the suffix of their target platform
+	if platformDir := filepath.Base(filepath.Dir(binaryPath)); IsPlatformDir(platformDir) {
+		goExe = ""
+		if strings.HasPrefix(platformDir, "windows_") {
+			goExe = ".exe"
+		}
+	}
+
+	return strings.TrimSuffix(filepath.Base(binaryPath), goExe)
 }
 
-func (i *Introspecter) getModuleInfo(binaryPath string) (*parsedGoModuleInfo, error) {
-	moduleOutput, err := i.cmdRunner.RunGoCommand("version", "-m", binaryPath)
-	if err != nil {
-		return nil, fmt.Errorf("could not retrieve version information about binary %s: %w\n%v", binaryPath, err, moduleOutput)
-	}
-	goModuleInfo := findModuleURLInModuleOutput(moduleOutput)
-	if goModuleInfo == nil {
-		return nil, fmt.Errorf("could not parse module information for binary %s", binaryPath)
-	}
-	goModuleInfo.buildTags = findBuildTagsInModuleOutput(moduleOutput)
-	if len(goModuleInfo.buildTags) > 0 {
-		i.logger.Sugar().Debugf("found build tags for binary %s: %v", binaryPath, goModuleInfo.buildTags)
-	} else {
-		i.logger.Sugar().Debugf("no build tags found for binary %s", binaryPath)
+func newGoBinary(binaryName, binaryPath string, buildInfo *debug.BuildInfo) GoBinary {
+	version := buildInfo.Main.Version
+	if buildInfo.Main.Path == "" {
+		// NOTE: Binaries built from source (using `go build`) do not contain a
+		// `mod` entry on go 1.18.
+		// If that happens, we behave as on older go versions (the version is
+		// "(devel)")
+		version = "(devel)"
 	}
 
-	return goModuleInfo, nil
+	return GoBinary{
+		ModuleURL: buildInfo.Main.Path,
+		PathURL:   buildInfo.Path,
+		Version:   version,
+		Name:      binaryName,
+		Path:      binaryPath,
+		BuildTags: findBuildTags(buildInfo.Settings),
+		GoVersion: buildInfo.GoVersion,
+		Deps:      buildInfo.Deps,
+		Settings:  buildInfo.Settings,
+	}
 }
 
-func (i *Introspecter) getLatestModuleVersion(moduleURL string) (string, error) {
-	latestVersionModule := fmt.Sprintf("%s@latest", moduleURL)
-	return i.cmdRunner.RunGoCommand("list", "-m", "-f", "{{.Version}}", latestVersionModule)
+func (i *Introspecter) getModuleVersion(ctx context.Context, moduleURL, query string) (string, error) {
+	moduleQuery := fmt.Sprintf("%s@%s", moduleURL, query)
+	return i.cmdRunner.RunGoCommand(ctx, "list", "-m", "-f", "{{.Version}}", moduleQuery)
 }
 
-func findModuleURLInModuleOutput(output string) *parsedGoModuleInfo {
-	r := regexp.MustCompile(`\s(path|mod)\s+([^\s]+)(\s+([^\s]+))?`)
-
-	goModuleInfo := parsedGoModuleInfo{}
-
-	matchedPath, matchedMod := false, false
+// findNewMajorVersion looks for newer major versions of the module, which
+// have a different module path (for example `mvdan.cc/sh/v4` for
+// `mvdan.cc/sh/v3`).
+//
+// It probes the consecutive major versions until one does not exist or only
+// has prereleases, and returns the module path and the latest version of the
+// newest one. It returns empty strings if there is no newer major version.
+func (i *Introspecter) findNewMajorVersion(ctx context.Context, moduleURL string) (newModuleURL, version string) {
+	prefix, pathMajor, ok := module.SplitPathVersion(moduleURL)
+	if !ok {
+		return "", ""
+	}
 
-	for _, l := range strings.Split(output, "\n") {
-		matches := r.FindStringSubmatch(l)
-		if len(matches) == 0 {
-			continue
+	// NOTE: v0 and v1 modules have no major version suffix
+	separator, major := "/v", 1
+	if pathMajor != "" {
+		var err error
+		separator = pathMajor[:2]
+		major, err = strconv.Atoi(pathMajor[2:])
+		if err != nil {
+			return "", ""
 		}
+	} else if strings.HasPrefix(moduleURL, "gopkg.in/") {
+		return "", ""
+	}
 
-		switch matches[1] {
-		case "path":
-			goModuleInfo.pathURL = matches[2]
-			matchedPath = true
-		case "mod":
-			goModuleInfo.moduleURL = matches[2]
-			goModuleInfo.version = matches[4]
-			matchedMod = true
-		default:
-			panic(fmt.Sprintf("unknown match %s", matches[0]))
+	for next := major + 1; ; next++ {
+		candidate := fmt.Sprintf("%s%s%d", prefix, separator, next)
+		candidateVersion, err := i.getModuleVersion(ctx, candidate, "latest")
+		if err != nil {
+			i.logger.Debug("no newer major version", zap.String("module", candidate), zap.Error(err))
+			return newModuleURL, version
+		}
+		// NOTE: `@latest` resolves to a prerelease if there are no releases.
+		// Prereleases are not offered, so they are not installed with
+		// --allow-major without asking for them.
+		if semver.Prerelease(candidateVersion) != "" {
+			i.logger.Debug("newer major version only has prereleases", zap.String("module", candidate), zap.String("version", candidateVersion))
+			return newModuleURL, version
 		}
-	}
 
-	if !matchedPath {
-		return nil
+		newModuleURL, version = candidate, candidateVersion
 	}
+}
 
-	if !matchedMod {
-		// NOTE: Binaries built from source (using `go build`) do not contain a
-		// `mod` entry on go 1.18.
-		// If that happens, we behave as on older go versions (the version is
-		// "(devel)")
-		goModuleInfo.version = "(devel)"
+// findModuleURL finds the module that contains the package.
+//
+// It checks whether the package path or any of its parent paths is a module.
+func (i *Introspecter) findModuleURL(ctx context.Context, pathURL string) (string, error) {
+	for candidate := pathURL; strings.Contains(candidate, "/"); candidate = path.Dir(candidate) {
+		if _, err := i.getModuleVersion(ctx, candidate, "latest"); err == nil {
+			return candidate, nil
+		}
 	}
 
-	return &goModuleInfo
+	return "", fmt.Errorf("could not find the module containing package %s", pathURL)
 }
 
-func findBuildTagsInModuleOutput(output string) []string {
-	r := regexp.MustCompile((`^\s+build\s+-tags=(([^,]+)(,[^,]+)*)$`))
-
-	var buildTags []string
-
-	for _, l := range strings.Split(output, "\n") {
-		matches := r.FindStringSubmatch(l)
-		if len(matches) == 0 {
-			continue
+func findBuildTags(settings []debug.BuildSetting) []string {
+	for _, setting := range settings {
+		if setting.Key == "-tags" && setting.Value != "" {
+			return strings.Split(setting.Value, ",")
 		}
-
-		buildTags = strings.Split(matches[1], ",")
-		break
 	}
 
-	return buildTags
+	return nil
 }
@@ -1,8 +1,10 @@
 package gobinaries_test
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
+	"runtime/debug"
 	"testing"
 
 	"github.com/Gelio/go-global-update/internal/gobinaries"
@@ -25,12 +27,13 @@ func TestExtractValidModuleURL(t *testing.T) {
 					gobinariestest.GetLatestVersionMockResponse(mockBinary.Binary),
 				},
 			}
+			buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
 
-			introspecter := gobinaries.NewIntrospecter(&cmdRunner, gobinariestest.GOBIN, zap.NewNop())
+			introspecter := gobinaries.NewIntrospecter(&cmdRunner, &buildInfoReader, "", zap.NewNop())
 
-			binary, err := introspecter.Introspect(mockBinary.Binary.Name)
-			assert.Nil(t, err)
-			assert.Equal(t, mockBinary.Binary, binary)
+			results := gobinaries.IntrospectBinaries(context.Background(), &introspecter, []string{mockBinary.Binary.Path})
+			assert.Nil(t, results[0].Error)
+			assert.Equal(t, mockBinary.Binary, results[0].Binary)
 		})
 	}
 }
@@ -44,9 +47,10 @@ shfmt: go1.17
 `),
 		},
 	}
-	introspecter := gobinaries.NewIntrospecter(&cmdRunner, gobin, zap.NewNop())
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	introspecter := gobinaries.NewIntrospecter(&cmdRunner, &buildInfoReader, "", zap.NewNop())
 
-	_, err := introspecter.Introspect("shfmt")
+	_, err := introspecter.Introspect(context.Background(), filepath.Join(gobin, "shfmt"))
 	assert.NotNil(t, err)
 }
 
@@ -60,12 +64,13 @@ func TestExtractLatestVersion(t *testing.T) {
 			gobinariestest.GetLatestVersionMockResponse(mockBinary.Binary),
 		},
 	}
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
 
-	introspecter := gobinaries.NewIntrospecter(&cmdRunner, gobinariestest.GOBIN, zap.NewNop())
-	binary, err := introspecter.Introspect(mockBinary.Binary.Name)
-	assert.Nil(t, err)
-	assert.Equal(t, mockBinary.Binary, binary)
-	assert.True(t, binary.UpgradePossible())
+	introspecter := gobinaries.NewIntrospecter(&cmdRunner, &buildInfoReader, "", zap.NewNop())
+	results := gobinaries.IntrospectBinaries(context.Background(), &introspecter, []string{mockBinary.Binary.Path})
+	assert.Nil(t, results[0].Error)
+	assert.Equal(t, mockBinary.Binary, results[0].Binary)
+	assert.True(t, results[0].Binary.UpgradePossible())
 }
 
 func TestBuiltFromSourceOnGo116And117(t *testing.T) {
@@ -76,6 +81,27 @@ func TestBuiltFromSourceOnGo116And117(t *testing.T) {
 			PathURL:   "command-line-arguments",
 			ModuleURL: "github.com/Gelio/go-global-update",
 			Version:   "(devel)",
+			GoVersion: "go1.17",
+			Deps: []*debug.Module{
+				{Path: "github.com/cpuguy83/go-md2man/v2", Version: "v2.0.0-20190314233015-f79a8a8ca69d", Sum: "h1:U+s90UTSYgptZMwQh2aRr3LuazLJIa+Pg3Kc1ylSYVY="},
+				{Path: "github.com/russross/blackfriday/v2", Version: "v2.0.1", Sum: "h1:lPqVAte+HuHNfhJ/0LC98ESWRz8afy9tM/0RK8m9o+Q="},
+				{Path: "github.com/shurcooL/sanitized_anchor_name", Version: "v1.0.0", Sum: "h1:PdmoCO6wvbs+7yrJyMORt4/BmY5IYyJwS/kOiWx8mHo="},
+				{Path: "github.com/urfave/cli/v2", Version: "v2.3.0", Sum: "h1:qph92Y649prgesehzOrQjdWyxFOp/QVM+6imKHad91M="},
+				{Path: "go.uber.org/atomic", Version: "v1.9.0", Sum: "h1:ECmE8Bn/WFTYwEW/bpKD3M8VtR/zQVbavAoalC1PYyE="},
+				{Path: "go.uber.org/multierr", Version: "v1.8.0", Sum: "h1:dg6GjLku4EH+249NNmoIciG9N/jURbDG+pFlTkhzIC8="},
+				{Path: "go.uber.org/zap", Version: "v1.21.0", Sum: "h1:WefMeulhovoZ2sYXz7st6K0sLj7bBhpiFaud4r4zST8="},
+			},
+			Settings: []debug.BuildSetting{
+				{Key: "-compiler", Value: "gc"},
+				{Key: "CGO_ENABLED", Value: "1"},
+				{Key: "CGO_CFLAGS", Value: ""},
+				{Key: "CGO_CPPFLAGS", Value: ""},
+				{Key: "CGO_CXXFLAGS", Value: ""},
+				{Key: "CGO_LDFLAGS", Value: ""},
+				{Key: "GOARCH", Value: "amd64"},
+				{Key: "GOOS", Value: "linux"},
+				{Key: "GOAMD64", Value: "v1"},
+			},
 		},
 		ModuleInfo: `
 go-global-update: go1.17
@@ -109,9 +135,10 @@ go-global-update: go1.17
 			latestVersionMockResponse,
 		},
 	}
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
 
-	introspecter := gobinaries.NewIntrospecter(&cmdRunner, gobinariestest.GOBIN, zap.NewNop())
-	binary, err := introspecter.Introspect(mockBinary.Binary.Name)
+	introspecter := gobinaries.NewIntrospecter(&cmdRunner, &buildInfoReader, "", zap.NewNop())
+	binary, err := introspecter.Introspect(context.Background(), mockBinary.Binary.Path)
 	assert.Nil(t, err)
 	assert.Equal(t, mockBinary.Binary, binary)
 	assert.True(t, binary.BuiltFromSource())
@@ -121,10 +148,32 @@ go-global-update: go1.17
 func TestMissingModLineOnGo118(t *testing.T) {
 	mockBinary := gobinariestest.MockBinary{
 		Binary: gobinaries.GoBinary{
-			Name:    "go-global-update",
-			Path:    filepath.Join(gobinariestest.GOBIN, "go-global-update"),
-			PathURL: "command-line-arguments",
-			Version: "(devel)",
+			Name:      "go-global-update",
+			Path:      filepath.Join(gobinariestest.GOBIN, "go-global-update"),
+			PathURL:   "command-line-arguments",
+			Version:   "(devel)",
+			GoVersion: "go1.18",
+			Deps: []*debug.Module{
+				{Path: "github.com/Gelio/go-global-update", Version: "(devel)"},
+				{Path: "github.com/cpuguy83/go-md2man/v2", Version: "v2.0.0-20190314233015-f79a8a8ca69d", Sum: "h1:U+s90UTSYgptZMwQh2aRr3LuazLJIa+Pg3Kc1ylSYVY="},
+				{Path: "github.com/russross/blackfriday/v2", Version: "v2.0.1", Sum: "h1:lPqVAte+HuHNfhJ/0LC98ESWRz8afy9tM/0RK8m9o+Q="},
+				{Path: "github.com/shurcooL/sanitized_anchor_name", Version: "v1.0.0", Sum: "h1:PdmoCO6wvbs+7yrJyMORt4/BmY5IYyJwS/kOiWx8mHo="},
+				{Path: "github.com/urfave/cli/v2", Version: "v2.3.0", Sum: "h1:qph92Y649prgesehzOrQjdWyxFOp/QVM+6imKHad91M="},
+				{Path: "go.uber.org/atomic", Version: "v1.9.0", Sum: "h1:ECmE8Bn/WFTYwEW/bpKD3M8VtR/zQVbavAoalC1PYyE="},
+				{Path: "go.uber.org/multierr", Version: "v1.8.0", Sum: "h1:dg6GjLku4EH+249NNmoIciG9N/jURbDG+pFlTkhzIC8="},
+				{Path: "go.uber.org/zap", Version: "v1.21.0", Sum: "h1:WefMeulhovoZ2sYXz7st6K0sLj7bBhpiFaud4r4zST8="},
+			},
+			Settings: []debug.BuildSetting{
+				{Key: "-compiler", Value: "gc"},
+				{Key: "CGO_ENABLED", Value: "1"},
+				{Key: "CGO_CFLAGS", Value: ""},
+				{Key: "CGO_CPPFLAGS", Value: ""},
+				{Key: "CGO_CXXFLAGS", Value: ""},
+				{Key: "CGO_LDFLAGS", Value: ""},
+				{Key: "GOARCH", Value: "amd64"},
+				{Key: "GOOS", Value: "linux"},
+				{Key: "GOAMD64", Value: "v1"},
+			},
 		},
 		ModuleInfo: `
 go-global-update: go1.18
@@ -158,9 +207,10 @@ go-global-update: go1.18
 			latestVersionMockResponse,
 		},
 	}
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
 
-	introspecter := gobinaries.NewIntrospecter(&cmdRunner, gobinariestest.GOBIN, zap.NewNop())
-	binary, err := introspecter.Introspect(mockBinary.Binary.Name)
+	introspecter := gobinaries.NewIntrospecter(&cmdRunner, &buildInfoReader, "", zap.NewNop())
+	binary, err := introspecter.Introspect(context.Background(), mockBinary.Binary.Path)
 	assert.Nil(t, err)
 	assert.Equal(t, mockBinary.Binary, binary)
 	assert.True(t, binary.BuiltFromSource())
@@ -192,6 +242,38 @@ func TestFindBuildTag(t *testing.T) {
 					ModuleURL: "github.com/Gelio/go-global-update",
 					Version:   "(devel)",
 					BuildTags: test.buildTagsArray,
+					GoVersion: "go1.23.1",
+					Deps: []*debug.Module{
+						{Path: "github.com/cpuguy83/go-md2man/v2", Version: "v2.0.0-20190314233015-f79a8a8ca69d", Sum: "h1:U+s90UTSYgptZMwQh2aRr3LuazLJIa+Pg3Kc1ylSYVY="},
+						{Path: "github.com/fatih/color", Version: "v1.13.0", Sum: "h1:8LOYc1KYPPmyKMuN8QV2DNRWNbLo6LZ0iLs8+mlH53w="},
+						{Path: "github.com/mattn/go-colorable", Version: "v0.1.9", Sum: "h1:sqDoxXbdeALODt0DAeJCVp38ps9ZogZEAXjus69YV3U="},
+						{Path: "github.com/mattn/go-isatty", Version: "v0.0.14", Sum: "h1:yVuAays6BHfxijgZPzw+3Zlu5yQgKGP2/hcQbHb7S9Y="},
+						{Path: "github.com/russross/blackfriday/v2", Version: "v2.0.1", Sum: "h1:lPqVAte+HuHNfhJ/0LC98ESWRz8afy9tM/0RK8m9o+Q="},
+						{Path: "github.com/shurcooL/sanitized_anchor_name", Version: "v1.0.0", Sum: "h1:PdmoCO6wvbs+7yrJyMORt4/BmY5IYyJwS/kOiWx8mHo="},
+						{Path: "github.com/urfave/cli/v2", Version: "v2.3.0", Sum: "h1:qph92Y649prgesehzOrQjdWyxFOp/QVM+6imKHad91M="},
+						{Path: "go.uber.org/atomic", Version: "v1.9.0", Sum: "h1:ECmE8Bn/WFTYwEW/bpKD3M8VtR/zQVbavAoalC1PYyE="},
+						{Path: "go.uber.org/multierr", Version: "v1.8.0", Sum: "h1:dg6GjLku4EH+249NNmoIciG9N/jURbDG+pFlTkhzIC8="},
+						{Path: "go.uber.org/zap", Version: "v1.21.0", Sum: "h1:WefMeulhovoZ2sYXz7st6K0sLj7bBhpiFaud4r4zST8="},
+						{Path: "golang.org/x/sys", Version: "v0.0.0-20210630005230-0f9fa26af87c", Sum: "h1:F1jZWGFhYfh0Ci55sIpILtKKK8p3i2/krTr0H1rg74I="},
+					},
+					Settings: []debug.BuildSetting{
+						{Key: "-buildmode", Value: "exe"},
+						{Key: "-compiler", Value: "gc"},
+						{Key: "-tags", Value: test.buildTagsString},
+						{Key: "DefaultGODEBUG", Value: "asynctimerchan=1,gotypesalias=0,httplaxcontentlength=1,httpmuxgo121=1,httpservecontentkeepheaders=1,netedns0=0,panicnil=1,tls10server=1,tls3des=1,tlskyber=0,tlsrsakex=1,tlsunsafeekm=1,winreadlinkvolume=0,winsymlink=0,x509keypairleaf=0,x509negativeserial=1"},
+						{Key: "CGO_ENABLED", Value: "1"},
+						{Key: "CGO_CFLAGS", Value: ""},
+						{Key: "CGO_CPPFLAGS", Value: ""},
+						{Key: "CGO_CXXFLAGS", Value: ""},
+						{Key: "CGO_LDFLAGS", Value: ""},
+						{Key: "GOARCH", Value: "arm64"},
+						{Key: "GOOS", Value: "darwin"},
+						{Key: "GOARM64", Value: "v8.0"},
+						{Key: "vcs", Value: "git"},
+						{Key: "vcs.revision", Value: "33e1a9214aa901d9c65c1143cff963f8bed3fc18"},
+						{Key: "vcs.time", Value: "2024-06-10T08:06:39Z"},
+						{Key: "vcs.modified", Value: "true"},
+					},
 				},
 				ModuleInfo: fmt.Sprintf(`
 go-global-update: go1.23.1
@@ -233,12 +315,174 @@ go-global-update: go1.23.1
 					gobinariestest.GetLatestVersionMockResponse(mockBinary.Binary),
 				},
 			}
+			buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
 
-			introspecter := gobinaries.NewIntrospecter(&cmdRunner, gobinariestest.GOBIN, zap.NewNop())
+			introspecter := gobinaries.NewIntrospecter(&cmdRunner, &buildInfoReader, "", zap.NewNop())
 
-			binary, err := introspecter.Introspect(mockBinary.Binary.Name)
-			assert.Nil(t, err)
-			assert.Equal(t, mockBinary.Binary, binary)
+			results := gobinaries.IntrospectBinaries(context.Background(), &introspecter, []string{mockBinary.Binary.Path})
+			assert.Nil(t, results[0].Error)
+			assert.Equal(t, mockBinary.Binary, results[0].Binary)
 		})
 	}
 }
+
+func TestResolveLatestVersionOncePerModule(t *testing.T) {
+	stringer := gobinariestest.GetStringerMockBinary()
+	goimports := gobinariestest.GetGoimportsMockBinary()
+	stringer.Binary.LatestVersion = "v0.1.12"
+	goimports.Binary.LatestVersion = "v0.1.12"
+
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinariestest.GetModuleInfoMockResponse(stringer),
+			gobinariestest.GetModuleInfoMockResponse(goimports),
+			gobinariestest.GetLatestVersionMockResponse(stringer.Binary),
+		},
+	}
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+
+	introspecter := gobinaries.NewIntrospecter(&cmdRunner, &buildInfoReader, "", zap.NewNop())
+	introspecter.SetFindNewMajorVersions(true)
+	results := gobinaries.IntrospectBinaries(context.Background(), &introspecter, []string{stringer.Binary.Path, goimports.Binary.Path})
+
+	assert.Nil(t, results[0].Error)
+	assert.Equal(t, stringer.Binary, results[0].Binary)
+	assert.Nil(t, results[1].Error)
+	assert.Equal(t, goimports.Binary, results[1].Binary)
+
+	var latestVersionCalls [][]string
+	for _, call := range cmdRunner.Calls() {
+		if call[0] == "list" {
+			latestVersionCalls = append(latestVersionCalls, call)
+		}
+	}
+	assert.Equal(t, [][]string{
+		gobinariestest.GetLatestVersionMockResponse(stringer.Binary).Args,
+		// NOTE: probing for a new major version is also done once per module
+		goclitest.GetLatestVersionMockResponse("golang.org/x/tools/v2", "").Args,
+	}, latestVersionCalls)
+}
+
+func TestLatestVersionErrorIsReportedForAllBinariesFromModule(t *testing.T) {
+	stringer := gobinariestest.GetStringerMockBinary()
+	goimports := gobinariestest.GetGoimportsMockBinary()
+
+	latestVersionMockResponse := gobinariestest.GetLatestVersionMockResponse(stringer.Binary)
+	latestVersionMockResponse.Error = fmt.Errorf("exit code 1")
+
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinariestest.GetModuleInfoMockResponse(stringer),
+			gobinariestest.GetModuleInfoMockResponse(goimports),
+			latestVersionMockResponse,
+		},
+	}
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+
+	introspecter := gobinaries.NewIntrospecter(&cmdRunner, &buildInfoReader, "", zap.NewNop())
+	results := gobinaries.IntrospectBinaries(context.Background(), &introspecter, []string{stringer.Binary.Path, goimports.Binary.Path})
+
+	assert.NotNil(t, results[0].Error)
+	assert.NotNil(t, results[1].Error)
+}
+
+func TestDiscoverNewMajorVersions(t *testing.T) {
+	shfmt := gobinariestest.GetShfmtMockBinary()
+	gofumpt := gobinariestest.GetGofumptMockBinary()
+
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinariestest.GetModuleInfoMockResponse(shfmt),
+			gobinariestest.GetModuleInfoMockResponse(gofumpt),
+			gobinariestest.GetLatestVersionMockResponse(shfmt.Binary),
+			gobinariestest.GetLatestVersionMockResponse(gofumpt.Binary),
+			goclitest.GetLatestVersionMockResponse("mvdan.cc/sh/v4", "v4.1.0"),
+			goclitest.GetLatestVersionMockResponse("mvdan.cc/sh/v5", "v5.0.0-rc.1"),
+		},
+	}
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+
+	introspecter := gobinaries.NewIntrospecter(&cmdRunner, &buildInfoReader, "", zap.NewNop())
+	introspecter.SetFindNewMajorVersions(true)
+	results := gobinaries.IntrospectBinaries(context.Background(), &introspecter, []string{shfmt.Binary.Path, gofumpt.Binary.Path})
+
+	shfmtBinary := results[0].Binary
+	assert.Nil(t, results[0].Error)
+	// NOTE: mvdan.cc/sh/v5 only has a prerelease, so it is not offered.
+	assert.Equal(t, "mvdan.cc/sh/v4", shfmtBinary.NewMajorModuleURL)
+	assert.Equal(t, "v4.1.0", shfmtBinary.NewMajorVersion)
+	assert.Equal(t, "mvdan.cc/sh/v4/cmd/shfmt", shfmtBinary.NewMajorPathURL())
+	assert.NotContains(t, cmdRunner.Calls(), goclitest.GetLatestVersionMockResponse("mvdan.cc/sh/v6", "").Args)
+
+	shfmtBinary.UseNewMajorVersion()
+	assert.Equal(t, "mvdan.cc/sh/v4/cmd/shfmt", shfmtBinary.InstallPath())
+	assert.Equal(t, "v4.1.0", shfmtBinary.LatestVersion)
+	assert.True(t, shfmtBinary.UpgradePossible())
+
+	assert.Nil(t, results[1].Error)
+	assert.Equal(t, "", results[1].Binary.NewMajorModuleURL, "mvdan.cc/gofumpt/v2 does not exist")
+	assert.Contains(t, cmdRunner.Calls(), goclitest.GetLatestVersionMockResponse("mvdan.cc/gofumpt/v2", "").Args)
+}
+
+func TestDoNotOfferNewMajorVersionsWithOnlyPrereleases(t *testing.T) {
+	shfmt := gobinariestest.GetShfmtMockBinary()
+
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinariestest.GetModuleInfoMockResponse(shfmt),
+			gobinariestest.GetLatestVersionMockResponse(shfmt.Binary),
+			goclitest.GetLatestVersionMockResponse("mvdan.cc/sh/v4", "v4.0.0-rc.1"),
+		},
+	}
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+
+	introspecter := gobinaries.NewIntrospecter(&cmdRunner, &buildInfoReader, "", zap.NewNop())
+	introspecter.SetFindNewMajorVersions(true)
+	results := gobinaries.IntrospectBinaries(context.Background(), &introspecter, []string{shfmt.Binary.Path})
+
+	assert.Nil(t, results[0].Error)
+	assert.Equal(t, "", results[0].Binary.NewMajorModuleURL)
+	assert.Equal(t, "", results[0].Binary.NewMajorVersion)
+}
+
+func TestDoNotDiscoverNewMajorVersionsForPinnedBinaries(t *testing.T) {
+	shfmt := gobinariestest.GetShfmtMockBinary()
+
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinariestest.GetModuleInfoMockResponse(shfmt),
+			goclitest.GetVersionQueryMockResponse(shfmt.Binary.ModuleURL, "v3.4", "v3.4.3"),
+			goclitest.GetLatestVersionMockResponse("mvdan.cc/sh/v4", "v4.1.0"),
+		},
+	}
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+
+	introspecter := gobinaries.NewIntrospecter(&cmdRunner, &buildInfoReader, "", zap.NewNop())
+	introspecter.SetFindNewMajorVersions(true)
+	introspecter.SetOverrides(map[string]gobinaries.Override{"shfmt": {VersionQuery: "v3.4"}})
+	results := gobinaries.IntrospectBinaries(context.Background(), &introspecter, []string{shfmt.Binary.Path})
+
+	assert.Nil(t, results[0].Error)
+	assert.Equal(t, "", results[0].Binary.NewMajorModuleURL)
+	assert.NotContains(t, cmdRunner.Calls(), goclitest.GetLatestVersionMockResponse("mvdan.cc/sh/v4", "").Args)
+}
+
+func TestDoNotDiscoverNewMajorVersionsByDefault(t *testing.T) {
+	shfmt := gobinariestest.GetShfmtMockBinary()
+
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinariestest.GetModuleInfoMockResponse(shfmt),
+			gobinariestest.GetLatestVersionMockResponse(shfmt.Binary),
+			goclitest.GetLatestVersionMockResponse("mvdan.cc/sh/v4", "v4.1.0"),
+		},
+	}
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+
+	introspecter := gobinaries.NewIntrospecter(&cmdRunner, &buildInfoReader, "", zap.NewNop())
+	results := gobinaries.IntrospectBinaries(context.Background(), &introspecter, []string{shfmt.Binary.Path})
+
+	assert.Nil(t, results[0].Error)
+	assert.Equal(t, "", results[0].Binary.NewMajorModuleURL)
+	assert.NotContains(t, cmdRunner.Calls(), goclitest.GetLatestVersionMockResponse("mvdan.cc/sh/v4", "").Args)
+}
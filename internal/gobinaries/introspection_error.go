@@ -0,0 +1,42 @@
+package gobinaries
+
+import (
+	"errors"
+	"os"
+)
+
+// IntrospectionErrorKind explains why an entry could not be introspected.
+type IntrospectionErrorKind string
+
+const (
+	IntrospectionErrorNotGoBinary      IntrospectionErrorKind = "not-go-binary"
+	IntrospectionErrorDirectory        IntrospectionErrorKind = "directory"
+	IntrospectionErrorNoBuildInfo      IntrospectionErrorKind = "no-build-info"
+	IntrospectionErrorPermissionDenied IntrospectionErrorKind = "permission-denied"
+	// IntrospectionErrorOther means the entry could be a go binary, but it
+	// could not be introspected, for example because it does not exist.
+	IntrospectionErrorOther IntrospectionErrorKind = "error"
+)
+
+// ClassifyIntrospectionError returns the kind of the error returned by
+// Introspect.
+func ClassifyIntrospectionError(err error) IntrospectionErrorKind {
+	switch {
+	case errors.Is(err, ErrNotGoBinary):
+		return IntrospectionErrorNotGoBinary
+	case errors.Is(err, ErrDirectory):
+		return IntrospectionErrorDirectory
+	case errors.Is(err, ErrNoBuildInfo):
+		return IntrospectionErrorNoBuildInfo
+	case errors.Is(err, os.ErrPermission):
+		return IntrospectionErrorPermissionDenied
+	default:
+		return IntrospectionErrorOther
+	}
+}
+
+// NotGoBinary determines whether the entry is known not to be a go binary
+// that can be updated.
+func (k IntrospectionErrorKind) NotGoBinary() bool {
+	return k != IntrospectionErrorOther
+}
@@ -0,0 +1,48 @@
+package gobinaries
+
+import "strings"
+
+// knownOS and knownArch are the values of GOOS and GOARCH supported by the go
+// toolchain (see `go tool dist list`).
+var knownOS = map[string]bool{
+	"aix":       true,
+	"android":   true,
+	"darwin":    true,
+	"dragonfly": true,
+	"freebsd":   true,
+	"illumos":   true,
+	"ios":       true,
+	"js":        true,
+	"linux":     true,
+	"netbsd":    true,
+	"openbsd":   true,
+	"plan9":     true,
+	"solaris":   true,
+	"wasip1":    true,
+	"windows":   true,
+}
+
+var knownArch = map[string]bool{
+	"386":      true,
+	"amd64":    true,
+	"arm":      true,
+	"arm64":    true,
+	"loong64":  true,
+	"mips":     true,
+	"mips64":   true,
+	"mips64le": true,
+	"mipsle":   true,
+	"ppc64":    true,
+	"ppc64le":  true,
+	"riscv64":  true,
+	"s390x":    true,
+	"wasm":     true,
+}
+
+// IsPlatformDir determines whether the directory name has the
+// `<goos>_<goarch>` format. `go install` puts cross-compiled binaries into
+// such subdirectories of GOPATH/bin.
+func IsPlatformDir(name string) bool {
+	goos, goarch, ok := strings.Cut(name, "_")
+	return ok && knownOS[goos] && knownArch[goarch]
+}
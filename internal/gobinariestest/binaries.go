@@ -2,6 +2,7 @@ package gobinariestest
 
 import (
 	"path/filepath"
+	"runtime/debug"
 
 	"github.com/Gelio/go-global-update/internal/gobinaries"
 	"github.com/Gelio/go-global-update/internal/goclitest"
@@ -31,6 +32,14 @@ func GetShfmtMockBinary() MockBinary {
 			Path:          filepath.Join(GOBIN, "shfmt"),
 			Version:       "v3.4.2",
 			LatestVersion: "v3.4.2",
+			GoVersion:     "go1.17",
+			Deps: []*debug.Module{
+				{Path: "github.com/google/renameio", Version: "v1.0.1", Sum: "h1:Lh/jXZmvZxb0BBeSY5VKEfidcbcbenKjZFzM/q0fSeU="},
+				{Path: "github.com/pkg/diff", Version: "v0.0.0-20210226163009-20ebb0f2a09e", Sum: "h1:aoZm08cpOy4WuID//EZDgcC4zIxODThtZNPirFr42+A="},
+				{Path: "golang.org/x/sys", Version: "v0.0.0-20210925032602-92d5a993a665", Sum: "h1:QOQNt6vCjMpXE7JSK5VvAzJC1byuN3FgTNSBwf+CJgI="},
+				{Path: "golang.org/x/term", Version: "v0.0.0-20210916214954-140adaaadfaf", Sum: "h1:Ihq/mm/suC88gF8WFcVwk+OV6Tq+wyA1O0E5UEvDglI="},
+				{Path: "mvdan.cc/editorconfig", Version: "v0.2.0", Sum: "h1:XL+7ys6ls/RKrkUNFQvEwIvNHh+JKx8Mj1pUV5wQxQE="},
+			},
 		},
 		ModuleInfo: `
 shfmt: go1.17
@@ -54,6 +63,14 @@ func GetGofumptMockBinary() MockBinary {
 			Path:          filepath.Join(GOBIN, "gofumpt"),
 			Version:       "v0.3.0",
 			LatestVersion: "v0.3.0",
+			GoVersion:     "go1.17",
+			Deps: []*debug.Module{
+				{Path: "github.com/google/go-cmp", Version: "v0.5.7", Sum: "h1:81/ik6ipDQS2aGcBfIN5dHDB36BwrStyeAQquSYCV4o="},
+				{Path: "golang.org/x/mod", Version: "v0.5.1", Sum: "h1:OJxoQ/rynoF0dcCdI7cLPktw/hR2cueqYfjm43oqK38="},
+				{Path: "golang.org/x/sync", Version: "v0.0.0-20210220032951-036812b2e83c", Sum: "h1:5KslGYwFpkhGh+Q16bwMP3cOontH8FOep7tGV86Y7SQ="},
+				{Path: "golang.org/x/sys", Version: "v0.0.0-20220209214540-3681064d5158", Sum: "h1:rm+CHSpPEEW2IsXUib1ThaHIjuBVZjxNgSKmBLFfD4c="},
+				{Path: "golang.org/x/tools", Version: "v0.1.9", Sum: "h1:j9KsMiaP1c3B0OTQGth0/k+miLGTgLsAFUCrF2vLcF8="},
+			},
 		},
 		ModuleInfo: `
 gofumpt: go1.17
@@ -67,3 +84,53 @@ gofumpt: go1.17
 `,
 	}
 }
+
+func GetGoimportsMockBinary() MockBinary {
+	return MockBinary{
+		Binary: gobinaries.GoBinary{
+			Name:          "goimports",
+			ModuleURL:     "golang.org/x/tools",
+			PathURL:       "golang.org/x/tools/cmd/goimports",
+			Path:          filepath.Join(GOBIN, "goimports"),
+			Version:       "v0.1.12",
+			LatestVersion: "v0.1.12",
+			GoVersion:     "go1.19",
+			Deps: []*debug.Module{
+				{Path: "golang.org/x/mod", Version: "v0.6.0-dev.0.20220419223038-86c51ed26bb4", Sum: "h1:6zppjxzCulZykYSLyVDYbneBfbaBIQPYMevg0bEwv2s="},
+				{Path: "golang.org/x/sys", Version: "v0.0.0-20220722155257-8c9f86f7a55f", Sum: "h1:v6hYoSR9T5oet+pMXwUWkbiVqx/63mlHjefrHmxwfeY="},
+			},
+		},
+		ModuleInfo: `
+goimports: go1.19
+        path    golang.org/x/tools/cmd/goimports
+        mod     golang.org/x/tools      v0.1.12 h1:VveCTK38A2rkS8ZqFY25HIDFscX5X9OoEhJd3quQmXU=
+        dep     golang.org/x/mod        v0.6.0-dev.0.20220419223038-86c51ed26bb4        h1:6zppjxzCulZykYSLyVDYbneBfbaBIQPYMevg0bEwv2s=
+        dep     golang.org/x/sys        v0.0.0-20220722155257-8c9f86f7a55f      h1:v6hYoSR9T5oet+pMXwUWkbiVqx/63mlHjefrHmxwfeY=
+`,
+	}
+}
+
+func GetStringerMockBinary() MockBinary {
+	return MockBinary{
+		Binary: gobinaries.GoBinary{
+			Name:          "stringer",
+			ModuleURL:     "golang.org/x/tools",
+			PathURL:       "golang.org/x/tools/cmd/stringer",
+			Path:          filepath.Join(GOBIN, "stringer"),
+			Version:       "v0.1.12",
+			LatestVersion: "v0.1.12",
+			GoVersion:     "go1.19",
+			Deps: []*debug.Module{
+				{Path: "golang.org/x/mod", Version: "v0.6.0-dev.0.20220419223038-86c51ed26bb4", Sum: "h1:6zppjxzCulZykYSLyVDYbneBfbaBIQPYMevg0bEwv2s="},
+				{Path: "golang.org/x/sys", Version: "v0.0.0-20220722155257-8c9f86f7a55f", Sum: "h1:v6hYoSR9T5oet+pMXwUWkbiVqx/63mlHjefrHmxwfeY="},
+			},
+		},
+		ModuleInfo: `
+stringer: go1.19
+        path    golang.org/x/tools/cmd/stringer
+        mod     golang.org/x/tools      v0.1.12 h1:VveCTK38A2rkS8ZqFY25HIDFscX5X9OoEhJd3quQmXU=
+        dep     golang.org/x/mod        v0.6.0-dev.0.20220419223038-86c51ed26bb4        h1:6zppjxzCulZykYSLyVDYbneBfbaBIQPYMevg0bEwv2s=
+        dep     golang.org/x/sys        v0.0.0-20220722155257-8c9f86f7a55f      h1:v6hYoSR9T5oet+pMXwUWkbiVqx/63mlHjefrHmxwfeY=
+`,
+	}
+}
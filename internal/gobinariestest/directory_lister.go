@@ -1,7 +1,16 @@
 package gobinariestest
 
-type TestSuccessDirectoryLister struct{ Entries []string }
+type TestSuccessDirectoryLister struct {
+	Entries []string
+	// EntriesByDir are the entries of specific directories. If set, they are
+	// used instead of Entries.
+	EntriesByDir map[string][]string
+}
 
 func (l *TestSuccessDirectoryLister) ListDirectoryEntries(path string) ([]string, error) {
+	if l.EntriesByDir != nil {
+		return l.EntriesByDir[path], nil
+	}
+
 	return l.Entries, nil
 }
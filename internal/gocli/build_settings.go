@@ -0,0 +1,89 @@
+package gocli
+
+import (
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+)
+
+// BuildSettingsArgs translates the build settings recorded in a binary into
+// `go install` flags and environment variables that build the binary the same
+// way.
+//
+// Build tags, GOOS, and GOARCH are not included. They are handled separately.
+// Settings that cannot be replicated are returned as dropped.
+func BuildSettingsArgs(settings []debug.BuildSetting) (flags []string, env []string, dropped []debug.BuildSetting) {
+	for _, setting := range settings {
+		key, value := setting.Key, setting.Value
+
+		switch {
+		case key == "-tags" || key == "GOOS" || key == "GOARCH":
+		case key == "DefaultGODEBUG" || strings.HasPrefix(key, "vcs"):
+			// NOTE: DefaultGODEBUG is derived from go.mod and the vcs settings
+			// are only recorded for binaries built in a repository.
+		case key == "-buildmode" && value == "exe", key == "-compiler" && value == "gc":
+			// NOTE: these are the defaults
+		case key == "-buildmode", key == "-compiler", key == "-gcflags", key == "-asmflags":
+			flags = append(flags, key+"="+value)
+		case key == "-trimpath", key == "-race", key == "-msan", key == "-asan":
+			if value == "true" {
+				flags = append(flags, key)
+			}
+		case key == "-ldflags":
+			ldflags, stamps, ok := splitLdflags(value)
+			if !ok {
+				dropped = append(dropped, setting)
+				continue
+			}
+			if len(ldflags) > 0 {
+				flags = append(flags, "-ldflags="+strings.Join(ldflags, " "))
+			}
+			if len(stamps) > 0 {
+				dropped = append(dropped, debug.BuildSetting{Key: key, Value: strings.Join(stamps, " ")})
+			}
+		case key == "-pgo" && filepath.Base(value) == "default.pgo":
+			// NOTE: the default profile of the main package is used again when
+			// the binary is reinstalled.
+		case strings.HasPrefix(key, "CGO_"), strings.HasPrefix(key, "GO"):
+			// NOTE: CGO_ENABLED, CGO_CFLAGS, GOEXPERIMENT, GOAMD64, GOARM, etc.
+			// Empty values mean the default.
+			if value != "" {
+				env = append(env, key+"="+value)
+			}
+		default:
+			dropped = append(dropped, setting)
+		}
+	}
+
+	return flags, env, dropped
+}
+
+// splitLdflags separates `-X` stamps from the other linker flags. The stamps
+// usually contain the version of the binary, so they cannot be reused for the
+// new version.
+//
+// ok is false if the flags contain quoted arguments, which are not supported.
+func splitLdflags(ldflags string) (flags []string, stamps []string, ok bool) {
+	if strings.ContainsAny(ldflags, `"'`) {
+		return nil, nil, false
+	}
+
+	fields := strings.Fields(ldflags)
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		switch {
+		case field == "-X" || field == "--X":
+			if i+1 < len(fields) {
+				i++
+				field += " " + fields[i]
+			}
+			stamps = append(stamps, field)
+		case strings.HasPrefix(field, "-X=") || strings.HasPrefix(field, "--X="):
+			stamps = append(stamps, field)
+		default:
+			flags = append(flags, field)
+		}
+	}
+
+	return flags, stamps, true
+}
@@ -0,0 +1,50 @@
+package gocli_test
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"github.com/Gelio/go-global-update/internal/gocli"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildSettingsArgs(t *testing.T) {
+	settings := []debug.BuildSetting{
+		{Key: "-buildmode", Value: "exe"},
+		{Key: "-compiler", Value: "gc"},
+		{Key: "-gcflags", Value: "all=-N -l"},
+		{Key: "-ldflags", Value: "-s -w -X main.version=v1.2.3 -X=main.commit=abcdef"},
+		{Key: "-race", Value: "true"},
+		{Key: "-tags", Value: "netgo"},
+		{Key: "-trimpath", Value: "true"},
+		{Key: "DefaultGODEBUG", Value: "panicnil=1"},
+		{Key: "CGO_ENABLED", Value: "1"},
+		{Key: "CGO_CFLAGS", Value: ""},
+		{Key: "GOARCH", Value: "amd64"},
+		{Key: "GOEXPERIMENT", Value: "rangefunc"},
+		{Key: "GOOS", Value: "linux"},
+		{Key: "GOAMD64", Value: "v3"},
+		{Key: "-unknown", Value: "true"},
+	}
+
+	flags, env, dropped := gocli.BuildSettingsArgs(settings)
+
+	assert.Equal(t, []string{"-gcflags=all=-N -l", "-ldflags=-s -w", "-race", "-trimpath"}, flags)
+	assert.Equal(t, []string{"CGO_ENABLED=1", "GOEXPERIMENT=rangefunc", "GOAMD64=v3"}, env)
+	assert.Equal(t, []debug.BuildSetting{
+		{Key: "-ldflags", Value: "-X main.version=v1.2.3 -X=main.commit=abcdef"},
+		{Key: "-unknown", Value: "true"},
+	}, dropped)
+}
+
+func TestBuildSettingsArgsWithQuotedLdflags(t *testing.T) {
+	settings := []debug.BuildSetting{
+		{Key: "-ldflags", Value: `-extldflags "-static"`},
+	}
+
+	flags, env, dropped := gocli.BuildSettingsArgs(settings)
+
+	assert.Empty(t, flags)
+	assert.Empty(t, env)
+	assert.Equal(t, settings, dropped)
+}
@@ -1,37 +1,121 @@
 package gocli
 
 import (
+	"bytes"
+	"context"
+	"os"
 	"os/exec"
 	"strings"
+	"time"
 
 	"go.uber.org/zap"
 )
 
 type GoCmdRunner interface {
-	RunGoCommand(args ...string) (string, error)
+	RunGoCommand(ctx context.Context, args ...string) (string, error)
+	// RunGoCommandWithOptions runs the go command with additional environment
+	// variables and in a specific working directory.
+	RunGoCommandWithOptions(ctx context.Context, options RunOptions, args ...string) (string, error)
 }
 
+// RunOptions customize how a single go command is run.
+type RunOptions struct {
+	// Env are additional environment variables in the `KEY=value` form. They
+	// take precedence over the environment variables of the process.
+	Env []string
+	// Dir is the working directory of the command. Defaults to the working
+	// directory of the process.
+	Dir string
+}
+
+// interruptGracePeriod is how long a go command has to exit after it is
+// interrupted before it is killed.
+const interruptGracePeriod = 5 * time.Second
+
 type RealGoCmdRunner struct {
 	logger *zap.Logger
+	// commandTimeout limits how long a single go command can run. There is no
+	// limit if it is zero.
+	commandTimeout time.Duration
 }
 
-func NewCmdRunner(logger *zap.Logger) RealGoCmdRunner {
+func NewCmdRunner(logger *zap.Logger, commandTimeout time.Duration) RealGoCmdRunner {
 	return RealGoCmdRunner{
 		logger,
+		commandTimeout,
 	}
 }
 
-func (runner *RealGoCmdRunner) RunGoCommand(args ...string) (string, error) {
+func (runner *RealGoCmdRunner) RunGoCommand(ctx context.Context, args ...string) (string, error) {
+	return runner.RunGoCommandWithOptions(ctx, RunOptions{}, args...)
+}
+
+func (runner *RealGoCmdRunner) RunGoCommandWithOptions(ctx context.Context, options RunOptions, args ...string) (string, error) {
+	// NOTE: do not start new commands once the run is interrupted
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	if runner.commandTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, runner.commandTimeout)
+		defer cancel()
+	}
+
 	cmd := exec.Command("go", args...)
-	bytes, err := cmd.CombinedOutput()
+	cmd.Dir = options.Dir
+	if len(options.Env) > 0 {
+		// NOTE: when a variable is set multiple times, the last value is used
+		cmd.Env = append(os.Environ(), options.Env...)
+	}
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Start()
+	if err == nil {
+		err = waitOrInterrupt(ctx, cmd, interruptGracePeriod)
+	}
 
 	runner.logger.Debug(
 		"go command output",
 		zap.Strings("args", args),
-		zap.String("output", string(bytes)),
+		zap.Strings("env", options.Env),
+		zap.String("dir", options.Dir),
+		zap.String("output", output.String()),
 		zap.Error(err),
 	)
 
 	// NOTE: always include output for displaying errors
-	return strings.TrimSpace(string(bytes)), err
+	return strings.TrimSpace(output.String()), err
+}
+
+// waitOrInterrupt waits for the command to exit. If the context is done
+// first, the command is interrupted, so it can clean up, and killed if it does
+// not exit within gracePeriod.
+func waitOrInterrupt(ctx context.Context, cmd *exec.Cmd, gracePeriod time.Duration) error {
+	waitErr := make(chan error, 1)
+	go func() {
+		waitErr <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-waitErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	// NOTE: interrupting is not supported on Windows
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		_ = cmd.Process.Kill()
+	}
+
+	select {
+	case <-waitErr:
+	case <-time.After(gracePeriod):
+		_ = cmd.Process.Kill()
+		<-waitErr
+	}
+
+	return ctx.Err()
 }
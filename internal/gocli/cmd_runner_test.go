@@ -0,0 +1,95 @@
+package gocli
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHelperProcess is not a real test. It is run as a separate process by
+// the tests of waitOrInterrupt.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GO_GLOBAL_UPDATE_HELPER_PROCESS") == "" {
+		return
+	}
+
+	if os.Getenv("GO_GLOBAL_UPDATE_HELPER_IGNORE_INTERRUPT") != "" {
+		signal.Ignore(os.Interrupt)
+	}
+	// NOTE: let the test know the signal handling is set up.
+	os.Stdout.WriteString("ready\n")
+	time.Sleep(time.Minute)
+	os.Exit(0)
+}
+
+// startHelperProcess starts TestHelperProcess and waits until it is ready to
+// be interrupted.
+func startHelperProcess(t *testing.T, ignoreInterrupt bool) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$")
+	cmd.Env = append(os.Environ(), "GO_GLOBAL_UPDATE_HELPER_PROCESS=1")
+	if ignoreInterrupt {
+		cmd.Env = append(cmd.Env, "GO_GLOBAL_UPDATE_HELPER_IGNORE_INTERRUPT=1")
+	}
+	stdout, err := cmd.StdoutPipe()
+	require.Nil(t, err)
+	require.Nil(t, cmd.Start())
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	require.Nil(t, err)
+	require.Equal(t, "ready\n", line)
+
+	return cmd
+}
+
+func TestWaitOrInterrupt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("interrupting processes is not supported on Windows")
+	}
+
+	testCases := []struct {
+		name            string
+		ignoreInterrupt bool
+		gracePeriod     time.Duration
+		minDuration     time.Duration
+		maxDuration     time.Duration
+	}{
+		{
+			name:        "stops when interrupted",
+			gracePeriod: 10 * time.Second,
+			maxDuration: 5 * time.Second,
+		},
+		{
+			name:            "is killed after the grace period",
+			ignoreInterrupt: true,
+			gracePeriod:     200 * time.Millisecond,
+			minDuration:     200 * time.Millisecond,
+			maxDuration:     5 * time.Second,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			cmd := startHelperProcess(t, testCase.ignoreInterrupt)
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			start := time.Now()
+			err := waitOrInterrupt(ctx, cmd, testCase.gracePeriod)
+			duration := time.Since(start)
+
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.GreaterOrEqual(t, duration, testCase.minDuration)
+			assert.Less(t, duration, testCase.maxDuration)
+			assert.False(t, cmd.ProcessState.Success(), "the command should not finish on its own")
+		})
+	}
+}
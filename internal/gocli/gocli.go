@@ -1,7 +1,10 @@
 package gocli
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"strings"
 )
 
@@ -15,19 +18,152 @@ type GoCLI struct {
 	cmdRunner GoCmdRunner
 }
 
-func (cli *GoCLI) GetEnvVar(name string) (string, error) {
-	return cli.cmdRunner.RunGoCommand("env", name)
+// GoEnv contains the go environment variables used by go-global-update.
+type GoEnv struct {
+	GOBIN       string
+	GOPATH      string
+	GOMODCACHE  string
+	GOEXE       string
+	GOVERSION   string
+	GOPROXY     string
+	GOSUMDB     string
+	GOPRIVATE   string
+	GONOPROXY   string
+	GONOSUMDB   string
+	GOINSECURE  string
+	GOFLAGS     string
+	GOTOOLCHAIN string
+	GOOS        string
+	GOARCH      string
 }
 
-func (cli *GoCLI) UpgradePackage(name string, buildTags []string) (string, error) {
+// GoEnvVariables are the names of the variables in GoEnv.
+var GoEnvVariables = []string{
+	"GOBIN",
+	"GOPATH",
+	"GOMODCACHE",
+	"GOEXE",
+	"GOVERSION",
+	"GOPROXY",
+	"GOSUMDB",
+	"GOPRIVATE",
+	"GONOPROXY",
+	"GONOSUMDB",
+	"GOINSECURE",
+	"GOFLAGS",
+	"GOTOOLCHAIN",
+	"GOOS",
+	"GOARCH",
+}
+
+// GetEnv reads all variables of GoEnv using a single `go env -json` command.
+func (cli *GoCLI) GetEnv(ctx context.Context) (GoEnv, error) {
+	args := append([]string{"env", "-json"}, GoEnvVariables...)
+	output, err := cli.cmdRunner.RunGoCommand(ctx, args...)
+	if err != nil {
+		if output != "" {
+			return GoEnv{}, fmt.Errorf("%w: %s", err, output)
+		}
+		return GoEnv{}, err
+	}
+
+	var env GoEnv
+	if err := json.Unmarshal([]byte(output), &env); err != nil {
+		return GoEnv{}, fmt.Errorf("could not parse the output of 'go env -json': %w", err)
+	}
+
+	return env, nil
+}
+
+// ModuleEnv returns the variables of GoEnv that affect which module versions
+// are downloaded and how they are built, in the `KEY=value` form. Empty
+// variables are left out.
+//
+// They keep the settings from the go env file when go commands are run with
+// GOENV=off.
+func (env GoEnv) ModuleEnv() []string {
+	var moduleEnv []string
+	for _, variable := range []struct {
+		key   string
+		value string
+	}{
+		{"GOPROXY", env.GOPROXY},
+		{"GOSUMDB", env.GOSUMDB},
+		{"GOPRIVATE", env.GOPRIVATE},
+		{"GONOPROXY", env.GONOPROXY},
+		{"GONOSUMDB", env.GONOSUMDB},
+		{"GOINSECURE", env.GOINSECURE},
+		{"GOFLAGS", env.GOFLAGS},
+		{"GOTOOLCHAIN", env.GOTOOLCHAIN},
+	} {
+		if variable.value != "" {
+			moduleEnv = append(moduleEnv, variable.key+"="+variable.value)
+		}
+	}
+
+	return moduleEnv
+}
+
+// BinDirs returns the directories with go binaries: GOBIN, followed by the
+// bin directory of each GOPATH entry. `go install` installs new binaries into
+// the first directory.
+func (env GoEnv) BinDirs() []string {
+	var binDirs []string
+	addBinDir := func(binDir string) {
+		for _, existingBinDir := range binDirs {
+			if existingBinDir == binDir {
+				return
+			}
+		}
+		binDirs = append(binDirs, binDir)
+	}
+
+	if env.GOBIN != "" {
+		addBinDir(env.GOBIN)
+	}
+	for _, gopath := range filepath.SplitList(env.GOPATH) {
+		if gopath != "" {
+			addBinDir(filepath.Join(gopath, "bin"))
+		}
+	}
+
+	return binDirs
+}
+
+// InstallOptions customize how a package is installed.
+type InstallOptions struct {
+	// Version is the version or the module version query to install.
+	// Defaults to `latest`.
+	Version string
+	// BuildTags correspond to the `-tags` option in `go install`.
+	BuildTags []string
+	// Flags are additional `go install` flags, for example `-trimpath`.
+	Flags []string
+	// Env are additional environment variables in the `KEY=value` form.
+	Env []string
+	// BinDir is the directory to install the binary into. Defaults to GOBIN.
+	BinDir string
+}
+
+func (cli *GoCLI) UpgradePackage(ctx context.Context, name string, options InstallOptions) (string, error) {
 	args := []string{"install"}
 
-	if len(buildTags) > 0 {
-		args = append(args, "-tags", strings.Join(buildTags, ","))
+	if len(options.BuildTags) > 0 {
+		args = append(args, "-tags", strings.Join(options.BuildTags, ","))
 	}
+	args = append(args, options.Flags...)
 
-	packageNameWithVersion := fmt.Sprintf("%s@latest", name)
+	version := options.Version
+	if version == "" {
+		version = "latest"
+	}
+	packageNameWithVersion := fmt.Sprintf("%s@%s", name, version)
 	args = append(args, packageNameWithVersion)
 
-	return cli.cmdRunner.RunGoCommand(args...)
+	env := options.Env
+	if options.BinDir != "" {
+		env = append([]string{"GOBIN=" + options.BinDir}, env...)
+	}
+
+	return cli.cmdRunner.RunGoCommandWithOptions(ctx, RunOptions{Env: env}, args...)
 }
@@ -0,0 +1,85 @@
+package gocli
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// HermeticGoCmdRunner runs go commands outside of any module or workspace, so
+// a go.mod or go.work file in the working directory of the process does not
+// affect which versions are resolved and installed.
+type HermeticGoCmdRunner struct {
+	cmdRunner GoCmdRunner
+	defaults  RunOptions
+}
+
+// NewHermeticCmdRunner returns a runner that runs go commands with GOWORK=off.
+//
+// If gobin is not empty, the commands run in gobin and install binaries into
+// it. Otherwise, they run in the temporary directory, in which go ignores
+// go.mod files.
+func NewHermeticCmdRunner(cmdRunner GoCmdRunner, gobin string) HermeticGoCmdRunner {
+	defaults := RunOptions{
+		Env: []string{"GOWORK=off"},
+		Dir: os.TempDir(),
+	}
+	if gobin != "" {
+		defaults.Env = append(defaults.Env, "GOBIN="+gobin)
+		defaults.Dir = gobin
+	}
+
+	return HermeticGoCmdRunner{
+		cmdRunner,
+		defaults,
+	}
+}
+
+func (runner *HermeticGoCmdRunner) RunGoCommand(ctx context.Context, args ...string) (string, error) {
+	return runner.RunGoCommandWithOptions(ctx, RunOptions{}, args...)
+}
+
+// RunGoCommandWithOptions runs the go command with the hermetic defaults. The
+// options take precedence over the defaults.
+func (runner *HermeticGoCmdRunner) RunGoCommandWithOptions(ctx context.Context, options RunOptions, args ...string) (string, error) {
+	dir := options.Dir
+	if dir == "" {
+		dir = runner.defaults.Dir
+	}
+
+	return runner.cmdRunner.RunGoCommandWithOptions(ctx, RunOptions{
+		Env: mergeEnv(runner.defaults.Env, options.Env),
+		Dir: dir,
+	}, args...)
+}
+
+// mergeEnv joins the environment variables. If a variable is set in both
+// lists, only the value from overrides is kept.
+func mergeEnv(env []string, overrides []string) []string {
+	merged := make([]string, 0, len(env)+len(overrides))
+	for _, variable := range env {
+		if !containsEnvKey(overrides, envKey(variable)) {
+			merged = append(merged, variable)
+		}
+	}
+
+	return append(merged, overrides...)
+}
+
+func containsEnvKey(env []string, key string) bool {
+	for _, variable := range env {
+		if envKey(variable) == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+func envKey(variable string) string {
+	if i := strings.Index(variable, "="); i >= 0 {
+		return variable[:i]
+	}
+
+	return variable
+}
@@ -0,0 +1,106 @@
+package gocli_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gelio/go-global-update/internal/gocli"
+	"github.com/Gelio/go-global-update/internal/goclitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestHermeticCmdRunnerEnv(t *testing.T) {
+	gobin := t.TempDir()
+	processGOBIN := filepath.Join(t.TempDir(), "process-bin")
+	callGOBIN := filepath.Join(t.TempDir(), "call-bin")
+	processGOWORK := filepath.Join(t.TempDir(), "go.work")
+
+	testCases := []struct {
+		name           string
+		gobin          string
+		env            []string
+		expectedGOWORK string
+		expectedGOBIN  string
+	}{
+		{
+			name:           "defaults override the process environment",
+			gobin:          gobin,
+			expectedGOWORK: "off",
+			expectedGOBIN:  gobin,
+		},
+		{
+			name:           "per-call env overrides the defaults",
+			gobin:          gobin,
+			env:            []string{"GOBIN=" + callGOBIN},
+			expectedGOWORK: "off",
+			expectedGOBIN:  callGOBIN,
+		},
+		{
+			name:           "GOWORK is turned off without GOBIN",
+			expectedGOWORK: "off",
+			expectedGOBIN:  processGOBIN,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Setenv("GOWORK", processGOWORK)
+			t.Setenv("GOBIN", processGOBIN)
+
+			cmdRunner := gocli.NewCmdRunner(zap.NewNop(), 0)
+			hermeticCmdRunner := gocli.NewHermeticCmdRunner(&cmdRunner, testCase.gobin)
+
+			output, err := hermeticCmdRunner.RunGoCommandWithOptions(context.Background(), gocli.RunOptions{Env: testCase.env}, "env", "GOWORK", "GOBIN")
+
+			require.Nil(t, err, output)
+			assert.Equal(t, []string{testCase.expectedGOWORK, testCase.expectedGOBIN}, strings.Split(output, "\n"))
+		})
+	}
+}
+
+func TestHermeticCmdRunnerDir(t *testing.T) {
+	gobin := filepath.Join("/home", "test", "go", "bin")
+	callDir := filepath.Join("/home", "test", "project")
+
+	testCases := []struct {
+		name        string
+		gobin       string
+		dir         string
+		expectedDir string
+	}{
+		{
+			name:        "GOBIN",
+			gobin:       gobin,
+			expectedDir: gobin,
+		},
+		{
+			name:        "temporary directory without GOBIN",
+			expectedDir: os.TempDir(),
+		},
+		{
+			name:        "per-call directory",
+			gobin:       gobin,
+			dir:         callDir,
+			expectedDir: callDir,
+		},
+	}
+
+	for _, testCase := range testCases {
+		cmdRunner := goclitest.TestGoCmdRunner{
+			Responses: []goclitest.MockResponse{
+				{Args: []string{"version"}, Dir: testCase.expectedDir},
+			},
+		}
+		hermeticCmdRunner := gocli.NewHermeticCmdRunner(&cmdRunner, testCase.gobin)
+
+		_, err := hermeticCmdRunner.RunGoCommandWithOptions(context.Background(), gocli.RunOptions{Dir: testCase.dir}, "version")
+
+		assert.Nil(t, err, testCase.name)
+	}
+}
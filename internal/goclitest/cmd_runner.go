@@ -1,44 +1,103 @@
 package goclitest
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"path/filepath"
+	"sync"
+
+	"github.com/Gelio/go-global-update/internal/gocli"
 )
 
 type MockResponse struct {
-	Args   []string
+	Args []string
+	// Env are the expected additional environment variables. If nil, the
+	// environment variables are not checked.
+	Env []string
+	// Dir is the expected working directory. If empty, the working directory
+	// is not checked.
+	Dir    string
 	Output string
 	Error  error
+	// Once makes the response match only the first call, so that later calls
+	// with the same arguments get the next matching response.
+	Once bool
 }
 
 type TestGoCmdRunner struct {
 	Responses []MockResponse
+
+	mutex sync.Mutex
+	calls [][]string
+	used  map[int]bool
+}
+
+// Calls returns the arguments of all go commands that were run.
+func (r *TestGoCmdRunner) Calls() [][]string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.calls
+}
+
+func (r *TestGoCmdRunner) RunGoCommand(ctx context.Context, args ...string) (string, error) {
+	return r.RunGoCommandWithOptions(ctx, gocli.RunOptions{}, args...)
 }
 
-func (r *TestGoCmdRunner) RunGoCommand(args ...string) (string, error) {
-	for _, v := range r.Responses {
-		if len(args) != len(v.Args) {
+func (r *TestGoCmdRunner) RunGoCommandWithOptions(ctx context.Context, options gocli.RunOptions, args ...string) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.calls = append(r.calls, args)
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	for i, v := range r.Responses {
+		if r.used[i] || !stringsEqual(args, v.Args) {
 			continue
 		}
-
-		match := true
-		for i, arg := range args {
-			if arg != v.Args[i] {
-				match = false
-				break
+		if v.Env != nil && !stringsEqual(options.Env, v.Env) {
+			continue
+		}
+		if v.Dir != "" && options.Dir != v.Dir {
+			continue
+		}
+		if v.Once {
+			if r.used == nil {
+				r.used = make(map[int]bool)
 			}
+			r.used[i] = true
 		}
-		if match {
-			return v.Output, v.Error
+
+		return v.Output, v.Error
+	}
+
+	return "", fmt.Errorf("could not match args: %v (env: %v, dir: %q)", args, options.Env, options.Dir)
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
 		}
 	}
 
-	return "", fmt.Errorf("could not match args: %v", args)
+	return true
 }
 
 func GetLatestVersionMockResponse(pathURL, version string) MockResponse {
+	return GetVersionQueryMockResponse(pathURL, "latest", version)
+}
+
+func GetVersionQueryMockResponse(moduleURL, query, version string) MockResponse {
 	return MockResponse{
-		Args:   []string{"list", "-m", "-f", "{{.Version}}", fmt.Sprintf("%s@latest", pathURL)},
+		Args:   []string{"list", "-m", "-f", "{{.Version}}", fmt.Sprintf("%s@%s", moduleURL, query)},
 		Output: version,
 	}
 }
@@ -49,3 +108,15 @@ func GetModuleInfoMockResponse(gobin, binaryName, output string) MockResponse {
 		Output: output,
 	}
 }
+
+func GetGoEnvMockResponse(env gocli.GoEnv) MockResponse {
+	output, err := json.Marshal(env)
+	if err != nil {
+		panic(err)
+	}
+
+	return MockResponse{
+		Args:   append([]string{"env", "-json"}, gocli.GoEnvVariables...),
+		Output: string(output),
+	}
+}
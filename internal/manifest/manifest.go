@@ -0,0 +1,87 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+)
+
+// Manifest lists installed binaries so that the same set of binaries can be
+// installed on another machine.
+type Manifest struct {
+	Binaries []Binary `json:"binaries"`
+}
+
+// Binary is a single binary in the Manifest.
+type Binary struct {
+	Name string `json:"name"`
+	// PathURL is the package the binary is installed from.
+	PathURL   string   `json:"path"`
+	Module    string   `json:"module"`
+	Version   string   `json:"version"`
+	BuildTags []string `json:"buildTags,omitempty"`
+}
+
+// New creates a Manifest from the introspected binaries.
+//
+// Binaries that could not be introspected or that were built from source
+// cannot be reinstalled using `go install` and are returned separately.
+func New(introspectionResults []gobinaries.IntrospectionResult) (manifest Manifest, skipped []gobinaries.IntrospectionResult) {
+	manifest.Binaries = []Binary{}
+
+	for _, result := range introspectionResults {
+		binary := result.Binary
+		if result.Error != nil || binary.BuiltFromSource() {
+			skipped = append(skipped, result)
+			continue
+		}
+
+		manifest.Binaries = append(manifest.Binaries, Binary{
+			Name:      binary.Name,
+			PathURL:   binary.PathURL,
+			Module:    binary.ModuleURL,
+			Version:   binary.Version,
+			BuildTags: binary.BuildTags,
+		})
+	}
+
+	return manifest, skipped
+}
+
+// Write writes the manifest as an indented JSON document.
+func Write(out io.Writer, manifest Manifest) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return fmt.Errorf("could not write manifest: %w", err)
+	}
+
+	return nil
+}
+
+// Read reads a manifest written by Write.
+func Read(in io.Reader) (Manifest, error) {
+	var manifest Manifest
+
+	decoder := json.NewDecoder(in)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&manifest); err != nil {
+		return Manifest{}, fmt.Errorf("could not parse manifest: %w", err)
+	}
+
+	for i, binary := range manifest.Binaries {
+		if binary.PathURL == "" || binary.Version == "" {
+			return Manifest{}, fmt.Errorf("invalid manifest: binary #%d (%s) is missing the path or the version", i+1, binary.Name)
+		}
+		// NOTE: the name is the file name in GOBIN. It must not point to GOBIN
+		// itself or outside of it.
+		if binary.Name == "" || binary.Name == "." || binary.Name == ".." || filepath.Base(binary.Name) != binary.Name {
+			return Manifest{}, fmt.Errorf("invalid manifest: binary #%d has an invalid name %q", i+1, binary.Name)
+		}
+	}
+
+	return manifest, nil
+}
@@ -0,0 +1,71 @@
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+	"github.com/Gelio/go-global-update/internal/gobinariestest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSkipsBinariesThatCannotBeReinstalled(t *testing.T) {
+	shfmt := gobinariestest.GetShfmtMockBinary().Binary
+	shfmt.BuildTags = []string{"netgo"}
+	builtFromSource := gobinariestest.GetGofumptMockBinary().Binary
+	builtFromSource.Version = "(devel)"
+
+	results := []gobinaries.IntrospectionResult{
+		{Binary: shfmt},
+		{Binary: builtFromSource},
+		{Binary: gobinaries.GoBinary{Name: "broken"}, Error: errors.New("no build info")},
+	}
+
+	manifest, skipped := New(results)
+
+	assert.Equal(t, Manifest{
+		Binaries: []Binary{
+			{
+				Name:      shfmt.Name,
+				PathURL:   shfmt.PathURL,
+				Module:    shfmt.ModuleURL,
+				Version:   shfmt.Version,
+				BuildTags: []string{"netgo"},
+			},
+		},
+	}, manifest)
+	assert.Equal(t, results[1:], skipped)
+}
+
+func TestWriteAndRead(t *testing.T) {
+	manifest, _ := New([]gobinaries.IntrospectionResult{
+		{Binary: gobinariestest.GetShfmtMockBinary().Binary},
+		{Binary: gobinariestest.GetGofumptMockBinary().Binary},
+	})
+
+	var buffer bytes.Buffer
+	require.Nil(t, Write(&buffer, manifest))
+
+	readManifest, err := Read(&buffer)
+	require.Nil(t, err)
+	assert.Equal(t, manifest, readManifest)
+}
+
+func TestReadInvalidManifest(t *testing.T) {
+	for _, contents := range []string{
+		`{"binaries": [{"name": "shfmt", "path": "mvdan.cc/sh/v3/cmd/shfmt"}]}`,
+		`{"binaries": [{"name": "", "path": "mvdan.cc/sh/v3/cmd/shfmt", "version": "v3.4.2"}]}`,
+		`{"binaries": [{"name": ".", "path": "mvdan.cc/sh/v3/cmd/shfmt", "version": "v3.4.2"}]}`,
+		`{"binaries": [{"name": "..", "path": "mvdan.cc/sh/v3/cmd/shfmt", "version": "v3.4.2"}]}`,
+		`{"binaries": [{"name": "../shfmt", "path": "mvdan.cc/sh/v3/cmd/shfmt", "version": "v3.4.2"}]}`,
+		`{"binaries": [{"name": "cmd/shfmt", "path": "mvdan.cc/sh/v3/cmd/shfmt", "version": "v3.4.2"}]}`,
+		`{"binaries": [], "unknown": true}`,
+		`not json`,
+	} {
+		_, err := Read(strings.NewReader(contents))
+		assert.NotNil(t, err, "expected an error for %s", contents)
+	}
+}
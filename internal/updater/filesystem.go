@@ -1,13 +1,60 @@
 package updater
 
-import "os"
+import (
+	"os"
+	"path/filepath"
+)
 
 type FilesystemUtils interface {
-	Chdir(dir string) error
+	MkdirAll(dir string) error
+	// MkdirTemp creates a new temporary directory in dir.
+	MkdirTemp(dir, pattern string) (string, error)
+	Remove(path string) error
+	RemoveAll(path string) error
+	Rename(oldPath, newPath string) error
+	EvalSymlinks(path string) (string, error)
+	// Readlink returns the destination of the symlink. It fails if the path
+	// is not a symlink.
+	Readlink(path string) (string, error)
+	Symlink(oldPath, newPath string) error
+	// Stat returns information about the file. Symlinks are followed.
+	Stat(path string) (os.FileInfo, error)
 }
 
 type Filesystem struct{}
 
-func (fs *Filesystem) Chdir(dir string) error {
-	return os.Chdir(dir)
+func (fs *Filesystem) MkdirAll(dir string) error {
+	return os.MkdirAll(dir, 0o755)
+}
+
+func (fs *Filesystem) MkdirTemp(dir, pattern string) (string, error) {
+	return os.MkdirTemp(dir, pattern)
+}
+
+func (fs *Filesystem) Remove(path string) error {
+	return os.Remove(path)
+}
+
+func (fs *Filesystem) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+func (fs *Filesystem) Rename(oldPath, newPath string) error {
+	return os.Rename(oldPath, newPath)
+}
+
+func (fs *Filesystem) EvalSymlinks(path string) (string, error) {
+	return filepath.EvalSymlinks(path)
+}
+
+func (fs *Filesystem) Readlink(path string) (string, error) {
+	return os.Readlink(path)
+}
+
+func (fs *Filesystem) Symlink(oldPath, newPath string) error {
+	return os.Symlink(oldPath, newPath)
+}
+
+func (fs *Filesystem) Stat(path string) (os.FileInfo, error) {
+	return os.Stat(path)
 }
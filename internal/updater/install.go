@@ -0,0 +1,208 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+	"github.com/Gelio/go-global-update/internal/gocli"
+)
+
+// VerificationError is returned when the installed binary does not match the
+// plan, for example because GOTOOLCHAIN, GOFLAGS, or the module proxy resolved
+// a different version.
+type VerificationError struct {
+	// Mismatches describe the differences between the installed binary and
+	// the plan.
+	Mismatches []string
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("the installed binary does not match the plan: %s", strings.Join(e.Mismatches, ", "))
+}
+
+// countFailed counts the failed install in the summary. Binaries that do not
+// match the plan are counted separately.
+func countFailed(summary *SummaryEvent, err error) {
+	var verificationErr *VerificationError
+	if errors.As(err, &verificationErr) {
+		summary.Mismatched++
+	} else {
+		summary.Failed++
+	}
+}
+
+// installer installs binaries atomically.
+//
+// Each binary is installed into a private staging directory next to its
+// destination first. Once the build information of the new binary matches the
+// plan, it is renamed into place. If anything fails, the installed binary is
+// left untouched.
+type installer struct {
+	goCLI           *gocli.GoCLI
+	buildInfoReader gobinaries.BuildInfoReader
+	goExe           string
+	fs              FilesystemUtils
+}
+
+func newInstaller(goCLI *gocli.GoCLI, buildInfoReader gobinaries.BuildInfoReader, env goEnvironment, fs FilesystemUtils) installer {
+	return installer{
+		goCLI:           goCLI,
+		buildInfoReader: buildInfoReader,
+		goExe:           env.GOEXE,
+		fs:              fs,
+	}
+}
+
+// install installs the binary into the directory it was found in. It returns
+// the new target of the symlink if it was changed.
+//
+// Binaries that were renamed keep their names and symlinks are kept according
+// to the mode, which `go install` would not do on its own.
+func (i *installer) install(
+	ctx context.Context,
+	binary gobinaries.GoBinary,
+	installOptions gocli.InstallOptions,
+	mode SymlinkMode,
+) (output string, newTarget string, err error) {
+	switch {
+	case binary.CrossCompiled():
+		output, err = i.installStaged(ctx, binary, installOptions, binary.Path)
+		return output, "", err
+
+	case binary.SymlinkTarget != "" && mode == SymlinkModeRetarget:
+		newTarget = retargetedPath(binary, i.goExe)
+		output, err = i.installStaged(ctx, binary, installOptions, newTarget)
+		if err != nil {
+			return output, "", err
+		}
+
+		return output, newTarget, retargetSymlink(binary.Path, newTarget, i.fs)
+
+	case binary.SymlinkTarget != "":
+		output, err = i.installStaged(ctx, binary, installOptions, binary.SymlinkTarget)
+		return output, "", err
+
+	default:
+		output, err = i.installStaged(ctx, binary, installOptions, binary.Path)
+		return output, "", err
+	}
+}
+
+// installStaged installs the binary into a temporary directory next to
+// destination, verifies it, and renames it to destination.
+func (i *installer) installStaged(
+	ctx context.Context,
+	binary gobinaries.GoBinary,
+	installOptions gocli.InstallOptions,
+	destination string,
+) (string, error) {
+	destinationDir := filepath.Dir(destination)
+	// NOTE: the temporary directory is on the same filesystem as the
+	// destination, so the binary can be renamed atomically.
+	stagingDir, err := i.fs.MkdirTemp(destinationDir, ".go-global-update-")
+	if err != nil {
+		return "", fmt.Errorf("could not create a temporary directory in %s: %w", destinationDir, err)
+	}
+	defer i.fs.RemoveAll(stagingDir)
+
+	stagedPath := i.stage(binary, &installOptions, stagingDir)
+	output, err := i.goCLI.UpgradePackage(ctx, binary.InstallPath(), installOptions)
+	if err != nil {
+		return output, err
+	}
+
+	// NOTE: the binary is already built. Finish moving it into place even if
+	// the run is interrupted in the meantime.
+	if err := i.verify(context.Background(), binary, installOptions, stagedPath); err != nil {
+		return output, err
+	}
+	if err := i.fs.Rename(stagedPath, destination); err != nil {
+		return output, fmt.Errorf("could not move the installed binary to %s: %w", destination, err)
+	}
+
+	return output, nil
+}
+
+// stage makes `go install` install the binary into stagingDir. It returns the
+// path of the installed binary.
+func (i *installer) stage(binary gobinaries.GoBinary, installOptions *gocli.InstallOptions, stagingDir string) string {
+	if !binary.CrossCompiled() {
+		installOptions.BinDir = stagingDir
+		return filepath.Join(stagingDir, gobinaries.ExecName(binary.InstallPath())+i.goExe)
+	}
+
+	// NOTE: cross-compiled binaries cannot be installed into GOBIN. They are
+	// installed into the bin directory of a temporary GOPATH instead.
+	installOptions.Env = append(installOptions.Env, "GOPATH="+stagingDir)
+	goos, goarch := binary.Setting("GOOS"), binary.Setting("GOARCH")
+	var goExe string
+	if goos == "windows" {
+		goExe = ".exe"
+	}
+
+	return filepath.Join(stagingDir, "bin", goos+"_"+goarch, gobinaries.ExecName(binary.InstallPath())+goExe)
+}
+
+// verify checks that the installed binary was built from the planned module,
+// version, and build tags.
+func (i *installer) verify(
+	ctx context.Context,
+	binary gobinaries.GoBinary,
+	installOptions gocli.InstallOptions,
+	installedPath string,
+) error {
+	buildInfo, err := i.buildInfoReader.ReadBuildInfo(ctx, installedPath)
+	if err != nil {
+		return fmt.Errorf("could not verify the installed binary: %w", err)
+	}
+
+	var mismatches []string
+	// NOTE: the module or the version are unknown when installing binaries
+	// from manifests without them, or at the latest version. The version is
+	// not checked either if a version query was installed instead of the exact
+	// version.
+	if module := binary.InstallModule(); module != "" && buildInfo.Main.Path != module {
+		mismatches = append(mismatches, fmt.Sprintf("module %s instead of %s", buildInfo.Main.Path, module))
+	}
+	if binary.LatestVersion != "" && installOptions.Version == binary.LatestVersion && buildInfo.Main.Version != binary.LatestVersion {
+		mismatches = append(mismatches, fmt.Sprintf("version %s instead of %s", buildInfo.Main.Version, binary.LatestVersion))
+	}
+
+	var installedBuildTags string
+	for _, setting := range buildInfo.Settings {
+		if setting.Key == "-tags" {
+			installedBuildTags = setting.Value
+		}
+	}
+	installed, planned := formatBuildTags(strings.Split(installedBuildTags, ",")), formatBuildTags(installOptions.BuildTags)
+	if installed != planned {
+		mismatches = append(mismatches, fmt.Sprintf("build tags %s instead of %s", installed, planned))
+	}
+
+	if len(mismatches) > 0 {
+		return &VerificationError{Mismatches: mismatches}
+	}
+
+	return nil
+}
+
+// formatBuildTags returns the sorted, comma-separated build tags, or "none".
+func formatBuildTags(buildTags []string) string {
+	var sortedBuildTags []string
+	for _, buildTag := range buildTags {
+		if buildTag != "" {
+			sortedBuildTags = append(sortedBuildTags, buildTag)
+		}
+	}
+	if len(sortedBuildTags) == 0 {
+		return "none"
+	}
+	sort.Strings(sortedBuildTags)
+
+	return strings.Join(sortedBuildTags, ",")
+}
@@ -0,0 +1,195 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gelio/go-global-update/internal/colors"
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+	"github.com/Gelio/go-global-update/internal/gobinariestest"
+	"github.com/Gelio/go-global-update/internal/goclitest"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestUpdateRenamedBinaries(t *testing.T) {
+	gofumptMockBinary := gobinariestest.GetGofumptMockBinary()
+	gofumptMockBinary.Binary.Name = "gf"
+	gofumptMockBinary.Binary.Path = filepath.Join(gobinariestest.GOBIN, "gf")
+	gofumptMockBinary.Binary.LatestVersion = "v0.4.0"
+	stagingDir := filepath.Join(gobinariestest.GOBIN, ".go-global-update-0")
+
+	logger := zap.NewNop()
+	options := Options{}
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		Entries: []string{gofumptMockBinary.Binary.Name},
+	}
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinMockResponse(),
+			gobinariestest.GetLatestVersionMockResponse(gofumptMockBinary.Binary),
+			gobinariestest.GetModuleInfoMockResponse(gofumptMockBinary),
+			{
+				Args: []string{"install", "mvdan.cc/gofumpt@v0.4.0"},
+				Env:  []string{"GOWORK=off", "GOBIN=" + stagingDir},
+			},
+			stagedBuildInfoMockResponse(gofumptMockBinary.Binary),
+		},
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := movingFilesystemUtils{}
+	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
+
+	err := UpdateBinaries(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, &fsutils, &mockSmokeChecker{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		fmt.Sprintf("rename %s %s", filepath.Join(stagingDir, "gofumpt"), gofumptMockBinary.Binary.Path),
+	}, fsutils.operations)
+	assert.Equal(t, strings.TrimSpace(`
+Binary      Current version      Status
+gf          v0.3.0               can upgrade to v0.4.0 (minor)
+
+Upgrading gf to v0.4.0 ... ✅
+`), strings.TrimSpace(output.String()))
+}
+
+func TestRejectUnexpectedInstalledVersion(t *testing.T) {
+	gofumptMockBinary := gobinariestest.GetGofumptMockBinary()
+	gofumptMockBinary.Binary.LatestVersion = "v0.4.0"
+
+	logger := zap.NewNop()
+	options := Options{}
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		Entries: []string{gofumptMockBinary.Binary.Name},
+	}
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinMockResponse(),
+			gobinariestest.GetLatestVersionMockResponse(gofumptMockBinary.Binary),
+			gobinariestest.GetModuleInfoMockResponse(gofumptMockBinary),
+			updateMockResponse(gofumptMockBinary.Binary, "", nil),
+			// NOTE: the module proxy answered inconsistently.
+			stagedBuildInfoMockResponse(withLatestVersion(gofumptMockBinary.Binary, "v0.5.0")),
+		},
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := movingFilesystemUtils{}
+	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
+
+	err := UpdateBinaries(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, &fsutils, &mockSmokeChecker{})
+
+	assert.NotNil(t, err)
+	assert.Empty(t, fsutils.operations, "the binary should not be replaced")
+	assert.Equal(t, strings.TrimSpace(`
+Binary       Current version      Status
+gofumpt      v0.3.0               can upgrade to v0.4.0 (minor)
+
+Upgrading gofumpt to v0.4.0 ... ❌
+    The installed binary does not match the plan:
+      - version v0.5.0 instead of v0.4.0
+
+Could not install 1 package(s)
+`), strings.TrimSpace(output.String()))
+}
+
+func TestInstallLatestWhenRequested(t *testing.T) {
+	gofumptMockBinary := gobinariestest.GetGofumptMockBinary()
+	gofumptMockBinary.Binary.LatestVersion = "v0.4.0"
+
+	logger := zap.NewNop()
+	options := Options{InstallLatest: true}
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		Entries: []string{gofumptMockBinary.Binary.Name},
+	}
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinMockResponse(),
+			gobinariestest.GetLatestVersionMockResponse(gofumptMockBinary.Binary),
+			gobinariestest.GetModuleInfoMockResponse(gofumptMockBinary),
+			{Args: []string{"install", "mvdan.cc/gofumpt@latest"}},
+			// NOTE: a new version was released after the latest version was
+			// checked. It is expected when installing @latest.
+			stagedBuildInfoMockResponse(withLatestVersion(gofumptMockBinary.Binary, "v0.5.0")),
+		},
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := movingFilesystemUtils{}
+	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
+
+	err := UpdateBinaries(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, &fsutils, &mockSmokeChecker{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		fmt.Sprintf("rename %s %s", filepath.Join(gobinariestest.GOBIN, ".go-global-update-0", "gofumpt"), gofumptMockBinary.Binary.Path),
+	}, fsutils.operations)
+}
+
+func TestReportMismatchedBuildTags(t *testing.T) {
+	shfmtMockBinary := gobinariestest.GetShfmtMockBinary()
+	shfmtMockBinary.Binary.BuildTags = []string{"netgo"}
+	shfmtMockBinary.Binary.LatestVersion = "v3.4.3"
+	shfmtMockBinary.ModuleInfo += "        build   -tags=netgo\n"
+
+	logger := zap.NewNop()
+	options := Options{}
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		Entries: []string{shfmtMockBinary.Binary.Name},
+	}
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinMockResponse(),
+			gobinariestest.GetLatestVersionMockResponse(shfmtMockBinary.Binary),
+			gobinariestest.GetModuleInfoMockResponse(shfmtMockBinary),
+			{Args: []string{"install", "-tags", "netgo", "mvdan.cc/sh/v3/cmd/shfmt@v3.4.3"}},
+			// NOTE: the build tags were dropped, for example by GOFLAGS.
+			stagedBuildInfoMockResponse(gobinaries.GoBinary{
+				Path:          shfmtMockBinary.Binary.Path,
+				PathURL:       shfmtMockBinary.Binary.PathURL,
+				ModuleURL:     shfmtMockBinary.Binary.ModuleURL,
+				LatestVersion: "v3.4.3",
+			}),
+		},
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := movingFilesystemUtils{}
+	reporter := NewJSONReporter(&output)
+
+	err := UpdateBinaries(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, &fsutils, &mockSmokeChecker{})
+
+	assert.EqualError(t, err, "could not install 1 package(s)")
+	assert.Empty(t, fsutils.operations, "the binary should not be replaced")
+	assert.JSONEq(t, `{
+		"dryRun": false,
+		"binaries": [
+			{
+				"name": "shfmt",
+				"pathURL": "mvdan.cc/sh/v3/cmd/shfmt",
+				"module": "mvdan.cc/sh/v3",
+				"currentVersion": "v3.4.2",
+				"latestVersion": "v3.4.3",
+				"status": "upgradable",
+				"upgradeState": "patch-behind",
+				"update": {
+					"outcome": "mismatched",
+					"mismatches": ["build tags none instead of netgo"]
+				}
+			}
+		]
+	}`, output.String())
+}
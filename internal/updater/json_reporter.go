@@ -0,0 +1,349 @@
+package updater
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+)
+
+// JSONReporter collects the events and writes a single JSON document with
+// the Report once the run is finished.
+type JSONReporter struct {
+	out    io.Writer
+	report Report
+	// binaryIndexes maps binary paths to their index in report.Binaries.
+	binaryIndexes map[string]int
+}
+
+func NewJSONReporter(out io.Writer) JSONReporter {
+	return JSONReporter{
+		out:           out,
+		binaryIndexes: make(map[string]int),
+	}
+}
+
+func (r *JSONReporter) Report(event Event) {
+	switch event := event.(type) {
+	case IntrospectedEvent:
+		r.report.Binaries = newBinaryReports(event.Results)
+		for i, result := range event.Results {
+			r.binaryIndexes[result.Binary.Path] = i
+		}
+
+	case SyncPlannedEvent:
+		r.report.Binaries = newSyncBinaryReports(event.Steps)
+		for i, step := range event.Steps {
+			r.binaryIndexes[step.Binary.Path] = i
+		}
+
+	case RemovedEvent:
+		updateReport := &UpdateReport{Outcome: UpdateOutcomeRemoved}
+		if event.Error != nil {
+			updateReport.Outcome = UpdateOutcomeFailed
+			updateReport.Output = event.Error.Error()
+		}
+		r.binaryReport(event.Binary).Update = updateReport
+
+	case PathAuditedEvent:
+		r.report.PathAudit = newPathAuditReport(event.Findings)
+
+	case SkippedFromSourceEvent:
+		r.binaryReport(event.Binary).Update = &UpdateReport{Outcome: UpdateOutcomeSkipped}
+
+	case SkippedSymlinkEvent:
+		r.binaryReport(event.Binary).Update = &UpdateReport{Outcome: UpdateOutcomeSkipped}
+
+	case InstallStartedEvent:
+		updateReport := &UpdateReport{Outcome: UpdateOutcomeReinstalled}
+		switch {
+		case event.Binary.Version == "":
+			updateReport.Outcome = UpdateOutcomeInstalled
+		case event.Binary.DowngradePossible():
+			updateReport.Outcome = UpdateOutcomeDowngraded
+		case event.Binary.UpgradePossible():
+			updateReport.Outcome = UpdateOutcomeUpgraded
+		}
+		r.binaryReport(event.Binary).Update = updateReport
+
+	case InstallFinishedEvent:
+		updateReport := r.binaryReport(event.Binary).Update
+		updateReport.Output = event.Output
+		updateReport.SymlinkTarget = event.SymlinkTarget
+		for _, setting := range event.DroppedSettings {
+			updateReport.DroppedSettings = append(updateReport.DroppedSettings, setting.Key+"="+setting.Value)
+		}
+		var verificationErr *VerificationError
+		if errors.As(event.Error, &verificationErr) {
+			updateReport.Outcome = UpdateOutcomeMismatched
+			updateReport.Mismatches = verificationErr.Mismatches
+		} else if event.Error != nil {
+			updateReport.Outcome = UpdateOutcomeFailed
+			if event.Output == "" {
+				updateReport.Output = event.Error.Error()
+			}
+		}
+
+	case SmokeCheckFailedEvent:
+		updateReport := r.binaryReport(event.Binary).Update
+		updateReport.Outcome = UpdateOutcomeFailed
+		updateReport.SmokeCheck = &SmokeCheckReport{
+			Args:       event.Args,
+			Output:     event.Output,
+			Error:      event.Error.Error(),
+			RolledBack: event.RolledBack,
+		}
+		if event.RollbackError != nil {
+			updateReport.SmokeCheck.RollbackError = event.RollbackError.Error()
+		}
+
+	case ProblemDetectedEvent:
+		updateReport := r.binaryReport(event.Binary).Update
+		updateReport.Problems = append(updateReport.Problems, event.Problem.name)
+
+	case SummaryEvent:
+		r.report.DryRun = event.DryRun
+		r.report.Interrupted = event.Interrupted
+
+		encoder := json.NewEncoder(r.out)
+		encoder.SetIndent("", "  ")
+		// NOTE: encoding can only fail when writing to out fails. Similarly to
+		// the TextReporter, such errors are ignored.
+		_ = encoder.Encode(r.report)
+	}
+}
+
+func (r *JSONReporter) binaryReport(binary gobinaries.GoBinary) *BinaryReport {
+	index, ok := r.binaryIndexes[binary.Path]
+	if !ok {
+		// NOTE: binaries that were not introspected (for example when importing
+		// a manifest) are added as they are installed.
+		index = len(r.report.Binaries)
+		r.report.Binaries = append(r.report.Binaries, BinaryReport{
+			Name:          binary.Name,
+			PathURL:       binary.PathURL,
+			Module:        binary.ModuleURL,
+			LatestVersion: binary.LatestVersion,
+		})
+		r.binaryIndexes[binary.Path] = index
+	}
+
+	return &r.report.Binaries[index]
+}
+
+type BinaryStatus string
+
+const (
+	BinaryStatusUpToDate        BinaryStatus = "up-to-date"
+	BinaryStatusUpgradable      BinaryStatus = "upgradable"
+	BinaryStatusBuiltFromSource BinaryStatus = "built-from-source"
+	BinaryStatusHeld            BinaryStatus = "held"
+	BinaryStatusError           BinaryStatus = BinaryStatus(gobinaries.IntrospectionErrorOther)
+	// The statuses of entries that are not go binaries that can be updated.
+	BinaryStatusNotGoBinary      BinaryStatus = BinaryStatus(gobinaries.IntrospectionErrorNotGoBinary)
+	BinaryStatusDirectory        BinaryStatus = BinaryStatus(gobinaries.IntrospectionErrorDirectory)
+	BinaryStatusNoBuildInfo      BinaryStatus = BinaryStatus(gobinaries.IntrospectionErrorNoBuildInfo)
+	BinaryStatusPermissionDenied BinaryStatus = BinaryStatus(gobinaries.IntrospectionErrorPermissionDenied)
+)
+
+type UpdateOutcome string
+
+const (
+	UpdateOutcomeInstalled   UpdateOutcome = "installed"
+	UpdateOutcomeUpgraded    UpdateOutcome = "upgraded"
+	UpdateOutcomeDowngraded  UpdateOutcome = "downgraded"
+	UpdateOutcomeRemoved     UpdateOutcome = "removed"
+	UpdateOutcomeReinstalled UpdateOutcome = "reinstalled"
+	UpdateOutcomeFailed      UpdateOutcome = "failed"
+	UpdateOutcomeSkipped     UpdateOutcome = "skipped"
+	// UpdateOutcomeMismatched means the built binary does not match the plan.
+	UpdateOutcomeMismatched UpdateOutcome = "mismatched"
+)
+
+// Report is the machine-readable summary of a single run.
+type Report struct {
+	DryRun bool `json:"dryRun"`
+	// Interrupted is set when the run was interrupted or timed out before all
+	// binaries were installed.
+	Interrupted bool           `json:"interrupted,omitempty"`
+	Binaries    []BinaryReport `json:"binaries"`
+	// PathAudit is only set by the path-audit command.
+	PathAudit *PathAuditReport `json:"pathAudit,omitempty"`
+}
+
+type PathAuditReport struct {
+	Findings []PathAuditFindingReport `json:"findings"`
+}
+
+type PathAuditFindingReport struct {
+	Kind           PathAuditFindingKind `json:"kind"`
+	Name           string               `json:"name"`
+	Path           string               `json:"path"`
+	CurrentVersion string               `json:"currentVersion"`
+	// LatestVersion is only set for outdated binaries.
+	LatestVersion string `json:"latestVersion,omitempty"`
+	// ShadowedByPath and ShadowedByVersion are only set for shadowed
+	// binaries.
+	ShadowedByPath    string `json:"shadowedByPath,omitempty"`
+	ShadowedByVersion string `json:"shadowedByVersion,omitempty"`
+}
+
+type BinaryReport struct {
+	Name           string `json:"name"`
+	PathURL        string `json:"pathURL,omitempty"`
+	Module         string `json:"module,omitempty"`
+	CurrentVersion string `json:"currentVersion,omitempty"`
+	LatestVersion  string `json:"latestVersion,omitempty"`
+	// VersionQuery is the version query the binary is pinned to.
+	VersionQuery string `json:"versionQuery,omitempty"`
+	// VersionRequested is set when VersionQuery was requested explicitly, on
+	// the command line or in the config file.
+	VersionRequested bool `json:"versionRequested,omitempty"`
+	// NewMajorVersion is the latest version of the newest major version of the
+	// module, which is installed from NewMajorPathURL.
+	NewMajorVersion string `json:"newMajorVersion,omitempty"`
+	NewMajorPathURL string `json:"newMajorPathURL,omitempty"`
+	// InstallPathURL is the package the binary will be installed from if it
+	// differs from PathURL.
+	InstallPathURL string `json:"installPathURL,omitempty"`
+	// Platform is the `<goos>/<goarch>` platform of cross-compiled binaries.
+	Platform string `json:"platform,omitempty"`
+	// SymlinkTarget is the resolved path of the file the binary links to if it
+	// is a symlink.
+	SymlinkTarget string `json:"symlinkTarget,omitempty"`
+	// Status is empty for binaries that were not introspected.
+	Status BinaryStatus `json:"status,omitempty"`
+	// UpgradeState compares the current version with the latest version.
+	UpgradeState gobinaries.UpgradeState `json:"upgradeState,omitempty"`
+	Error        string                  `json:"error,omitempty"`
+	// SyncAction is the planned action when syncing GOBIN with a manifest.
+	// When syncing, LatestVersion is the version from the manifest.
+	SyncAction SyncAction `json:"syncAction,omitempty"`
+	// Update is the outcome of updating the binary. It is nil if the update
+	// was not attempted.
+	Update *UpdateReport `json:"update,omitempty"`
+}
+
+type UpdateReport struct {
+	Outcome UpdateOutcome `json:"outcome"`
+	// Output is the captured output of `go install`.
+	Output string `json:"output,omitempty"`
+	// Problems are the codes of the common update problems that were
+	// detected.
+	Problems []string `json:"problems,omitempty"`
+	// DroppedSettings are the build settings (in the `key=value` form) that
+	// could not be replicated when installing the binary.
+	DroppedSettings []string `json:"droppedSettings,omitempty"`
+	// SymlinkTarget is the new target of the symlink if the binary is
+	// a symlink that was pointed to a newly installed file.
+	SymlinkTarget string `json:"symlinkTarget,omitempty"`
+	// Mismatches describe the differences between the installed binary and
+	// the plan when the outcome is "mismatched".
+	Mismatches []string `json:"mismatches,omitempty"`
+	// SmokeCheck is the failed smoke check. It is nil if the smoke check
+	// passed or was not configured.
+	SmokeCheck *SmokeCheckReport `json:"smokeCheck,omitempty"`
+}
+
+type SmokeCheckReport struct {
+	Args          []string `json:"args"`
+	Output        string   `json:"output,omitempty"`
+	Error         string   `json:"error"`
+	RolledBack    bool     `json:"rolledBack"`
+	RollbackError string   `json:"rollbackError,omitempty"`
+}
+
+func newBinaryReports(introspectionResults []gobinaries.IntrospectionResult) []BinaryReport {
+	binaryReports := make([]BinaryReport, len(introspectionResults))
+
+	for i, result := range introspectionResults {
+		binary := result.Binary
+		binaryReport := BinaryReport{
+			Name:             binary.Name,
+			PathURL:          binary.PathURL,
+			Module:           binary.ModuleURL,
+			CurrentVersion:   binary.Version,
+			LatestVersion:    binary.LatestVersion,
+			VersionQuery:     binary.VersionQuery,
+			VersionRequested: binary.VersionRequested,
+			NewMajorVersion:  binary.NewMajorVersion,
+			NewMajorPathURL:  binary.NewMajorPathURL(),
+			SymlinkTarget:    binary.SymlinkTarget,
+		}
+		if binary.PathChanged() {
+			binaryReport.InstallPathURL = binary.InstallPathURL
+		}
+		if binary.CrossCompiled() {
+			binaryReport.Platform = binary.Setting("GOOS") + "/" + binary.Setting("GOARCH")
+		}
+		if result.Error == nil {
+			binaryReport.UpgradeState = binary.UpgradeState()
+		}
+
+		switch {
+		case result.Error != nil:
+			binaryReport.Status = BinaryStatus(gobinaries.ClassifyIntrospectionError(result.Error))
+			binaryReport.Error = result.Error.Error()
+		case binary.BuiltFromSource() || binary.LatestVersion == "":
+			binaryReport.Status = BinaryStatusBuiltFromSource
+		case binary.Held:
+			binaryReport.Status = BinaryStatusHeld
+		case binary.UpgradePossible():
+			binaryReport.Status = BinaryStatusUpgradable
+		default:
+			binaryReport.Status = BinaryStatusUpToDate
+		}
+
+		binaryReports[i] = binaryReport
+	}
+
+	return binaryReports
+}
+
+func newSyncBinaryReports(steps []SyncStep) []BinaryReport {
+	binaryReports := make([]BinaryReport, len(steps))
+
+	for i, step := range steps {
+		binary := step.Binary
+		binaryReport := BinaryReport{
+			Name:           binary.Name,
+			PathURL:        binary.PathURL,
+			Module:         binary.ModuleURL,
+			CurrentVersion: binary.Version,
+			LatestVersion:  binary.LatestVersion,
+			SyncAction:     step.Action,
+			SymlinkTarget:  binary.SymlinkTarget,
+		}
+		if binary.PathChanged() {
+			binaryReport.InstallPathURL = binary.InstallPathURL
+		}
+		if step.Error != nil {
+			binaryReport.Status = BinaryStatus(gobinaries.ClassifyIntrospectionError(step.Error))
+			binaryReport.Error = step.Error.Error()
+		}
+
+		binaryReports[i] = binaryReport
+	}
+
+	return binaryReports
+}
+
+func newPathAuditReport(findings []PathAuditFinding) *PathAuditReport {
+	findingReports := make([]PathAuditFindingReport, len(findings))
+	for i, finding := range findings {
+		findingReports[i] = PathAuditFindingReport{
+			Kind:              finding.Kind,
+			Name:              finding.Binary.Name,
+			Path:              finding.Binary.Path,
+			CurrentVersion:    finding.Binary.Version,
+			ShadowedByPath:    finding.ShadowedByPath,
+			ShadowedByVersion: finding.ShadowedByVersion,
+		}
+		if finding.Kind == PathAuditOutdated {
+			findingReports[i].LatestVersion = finding.Binary.LatestVersion
+		}
+	}
+
+	return &PathAuditReport{Findings: findingReports}
+}
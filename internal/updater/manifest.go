@@ -0,0 +1,132 @@
+package updater
+
+import (
+	"context"
+	"io"
+
+	"github.com/Gelio/go-global-update/internal/config"
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+	"github.com/Gelio/go-global-update/internal/gocli"
+	"github.com/Gelio/go-global-update/internal/manifest"
+	"go.uber.org/zap"
+)
+
+// ExportManifest writes a manifest of the binaries in GOBIN to out.
+//
+// If binaryNames is empty, all binaries in GOBIN, GOPATH, and dirs are
+// exported. Binaries that cannot be reinstalled using `go install` are left
+// out with a warning.
+func ExportManifest(
+	ctx context.Context,
+	logger *zap.Logger,
+	binaryNames []string,
+	dirs []string,
+	out io.Writer,
+	cmdRunner gocli.GoCmdRunner,
+	lister gobinaries.DirectoryLister,
+	buildInfoReader gobinaries.BuildInfoReader,
+	fs FilesystemUtils,
+) error {
+	env, err := findGoEnvironment(ctx, logger, cmdRunner, dirs, fs)
+	if err != nil {
+		return err
+	}
+
+	hermeticCmdRunner := gocli.NewHermeticCmdRunner(cmdRunner, env.gobin())
+	introspecter := gobinaries.NewIntrospecter(&hermeticCmdRunner, buildInfoReader, env.GOEXE, logger)
+	binaryPaths, _, err := resolveBinaryPaths(binaryNames, lister, env)
+	if err != nil {
+		return err
+	}
+
+	// NOTE: the latest versions are not needed in the manifest, so there is no
+	// need to resolve them.
+	introspectionResults := gobinaries.ReadBinaries(ctx, &introspecter, binaryPaths)
+	exportedManifest, skipped := manifest.New(introspectionResults)
+	for _, result := range skipped {
+		if result.Error != nil {
+			logger.Warn("skipping binary that could not be introspected",
+				zap.String("binary", result.Binary.Name), zap.Error(result.Error))
+		} else {
+			logger.Warn("skipping binary built from source", zap.String("binary", result.Binary.Name))
+		}
+	}
+
+	return manifest.Write(out, exportedManifest)
+}
+
+type ImportOptions struct {
+	// Whether to install the latest versions instead of the versions from the
+	// manifest.
+	Latest bool
+	// Config contains per-binary settings from the config file.
+	Config config.Config
+}
+
+// ImportManifest installs all binaries from the manifest into GOBIN.
+//
+// The progress and the results are reported as events to the reporter.
+func ImportManifest(
+	ctx context.Context,
+	logger *zap.Logger,
+	importedManifest manifest.Manifest,
+	options ImportOptions,
+	reporter Reporter,
+	cmdRunner gocli.GoCmdRunner,
+	buildInfoReader gobinaries.BuildInfoReader,
+	fs FilesystemUtils,
+) error {
+	env, err := findGoEnvironment(ctx, logger, cmdRunner, nil, fs)
+	if err != nil {
+		return err
+	}
+
+	hermeticCmdRunner := gocli.NewHermeticCmdRunner(cmdRunner, env.gobin())
+	goCLI := gocli.New(&hermeticCmdRunner)
+	installer := newInstaller(&goCLI, buildInfoReader, env, fs)
+	var summary SummaryEvent
+	for _, manifestBinary := range importedManifest.Binaries {
+		if ctx.Err() != nil {
+			summary.Canceled++
+			continue
+		}
+
+		binary := gobinaries.GoBinary{
+			Name:      manifestBinary.Name,
+			Path:      env.newBinaryPath(manifestBinary.Name),
+			PathURL:   manifestBinary.PathURL,
+			ModuleURL: manifestBinary.Module,
+			BuildTags: manifestBinary.BuildTags,
+		}
+		if !options.Latest {
+			binary.LatestVersion = manifestBinary.Version
+			binary.VersionQuery = manifestBinary.Version
+		}
+
+		// NOTE: there are no build settings to replicate, because the binary
+		// is not installed yet.
+		installOptions, _ := getInstallOptions(binary, options.Config.Binaries[binary.Name], env)
+		reporter.Report(InstallStartedEvent{Binary: binary, BuildTags: installOptions.BuildTags})
+		// NOTE: binaries that were renamed keep their names.
+		installOutput, _, err := installer.install(ctx, binary, installOptions, SymlinkModeTarget)
+		reporter.Report(InstallFinishedEvent{Binary: binary, Output: installOutput, Error: err})
+
+		for _, problem := range FindCommonUpdateProblems(installOutput) {
+			reporter.Report(ProblemDetectedEvent{Binary: binary, Problem: problem})
+		}
+
+		if err != nil {
+			countFailed(&summary, err)
+		} else {
+			summary.Installed++
+		}
+	}
+	summary.Interrupted = ctx.Err() != nil
+	reporter.Report(summary)
+
+	if err := interruptedError(ctx); err != nil {
+		return err
+	}
+
+	return failedError(summary)
+}
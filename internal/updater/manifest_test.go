@@ -0,0 +1,170 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Gelio/go-global-update/internal/colors"
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+	"github.com/Gelio/go-global-update/internal/gobinariestest"
+	"github.com/Gelio/go-global-update/internal/goclitest"
+	"github.com/Gelio/go-global-update/internal/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestExportManifest(t *testing.T) {
+	shfmtMockBinary := gobinariestest.GetShfmtMockBinary()
+	shfmtMockBinary.ModuleInfo = fmt.Sprintf(`%s
+  build  -tags=netgo`, shfmtMockBinary.ModuleInfo)
+	gofumptMockBinary := gobinariestest.GetGofumptMockBinary()
+
+	logger := zap.NewNop()
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		Entries: []string{shfmtMockBinary.Binary.Name, gofumptMockBinary.Binary.Name},
+	}
+	// NOTE: there are no mock responses for the latest versions. Exporting
+	// must not resolve them.
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinMockResponse(),
+			gobinariestest.GetModuleInfoMockResponse(shfmtMockBinary),
+			gobinariestest.GetModuleInfoMockResponse(gofumptMockBinary),
+		},
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := mockFilesystemUtils{}
+
+	err := ExportManifest(context.Background(), logger, nil, nil, &output, &cmdRunner, &lister, &buildInfoReader, fsutils)
+	require.Nil(t, err)
+
+	exportedManifest, err := manifest.Read(&output)
+	require.Nil(t, err)
+	assert.Equal(t, manifest.Manifest{
+		Binaries: []manifest.Binary{
+			{
+				Name:      "shfmt",
+				PathURL:   shfmtMockBinary.Binary.PathURL,
+				Module:    shfmtMockBinary.Binary.ModuleURL,
+				Version:   shfmtMockBinary.Binary.Version,
+				BuildTags: []string{"netgo"},
+			},
+			{
+				Name:    "gofumpt",
+				PathURL: gofumptMockBinary.Binary.PathURL,
+				Module:  gofumptMockBinary.Binary.ModuleURL,
+				Version: gofumptMockBinary.Binary.Version,
+			},
+		},
+	}, exportedManifest)
+}
+
+func TestImportManifest(t *testing.T) {
+	importedManifest := manifest.Manifest{
+		Binaries: []manifest.Binary{
+			{
+				Name:      "shfmt",
+				PathURL:   "mvdan.cc/sh/v3/cmd/shfmt",
+				Module:    "mvdan.cc/sh/v3",
+				Version:   "v3.4.2",
+				BuildTags: []string{"netgo"},
+			},
+			{
+				Name:    "gofumpt",
+				PathURL: "mvdan.cc/gofumpt",
+				Module:  "mvdan.cc/gofumpt",
+				Version: "v0.2.1",
+			},
+		},
+	}
+
+	logger := zap.NewNop()
+	var output bytes.Buffer
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinMockResponse(),
+			{
+				Args: []string{"install", "-tags", "netgo", "mvdan.cc/sh/v3/cmd/shfmt@v3.4.2"},
+			},
+			stagedBuildInfoMockResponse(gobinaries.GoBinary{
+				Path:          gobinariestest.GetShfmtMockBinary().Binary.Path,
+				PathURL:       "mvdan.cc/sh/v3/cmd/shfmt",
+				ModuleURL:     "mvdan.cc/sh/v3",
+				LatestVersion: "v3.4.2",
+				BuildTags:     []string{"netgo"},
+			}),
+			{
+				Args:   []string{"install", "mvdan.cc/gofumpt@v0.2.1"},
+				Output: "go: mvdan.cc/gofumpt@v0.2.1: no matching versions",
+				Error:  errors.New("exit status 1"),
+			},
+		},
+	}
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := mockFilesystemUtils{}
+	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
+
+	err := ImportManifest(context.Background(), logger, importedManifest, ImportOptions{}, &reporter, &cmdRunner, &buildInfoReader, fsutils)
+
+	assert.NotNil(t, err)
+	assert.Equal(t, strings.TrimSpace(`
+Installing shfmt v3.4.2 (build tags: netgo) ... ✅
+
+Installing gofumpt v0.2.1 ... ❌
+    Could not install package
+go: mvdan.cc/gofumpt@v0.2.1: no matching versions
+
+Could not install 1 package(s)
+`), strings.TrimSpace(output.String()))
+}
+
+func TestImportManifestLatest(t *testing.T) {
+	importedManifest := manifest.Manifest{
+		Binaries: []manifest.Binary{
+			{
+				Name:    "gofumpt",
+				PathURL: "mvdan.cc/gofumpt",
+				Module:  "mvdan.cc/gofumpt",
+				Version: "v0.2.1",
+			},
+		},
+	}
+
+	logger := zap.NewNop()
+	var output bytes.Buffer
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinMockResponse(),
+			{
+				Args: []string{"install", "mvdan.cc/gofumpt@latest"},
+			},
+			stagedBuildInfoMockResponse(withLatestVersion(gobinariestest.GetGofumptMockBinary().Binary, "v0.4.0")),
+		},
+	}
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := mockFilesystemUtils{}
+	reporter := NewJSONReporter(&output)
+
+	err := ImportManifest(context.Background(), logger, importedManifest, ImportOptions{Latest: true}, &reporter, &cmdRunner, &buildInfoReader, fsutils)
+
+	require.Nil(t, err)
+	assert.JSONEq(t, `{
+		"dryRun": false,
+		"binaries": [
+			{
+				"name": "gofumpt",
+				"pathURL": "mvdan.cc/gofumpt",
+				"module": "mvdan.cc/gofumpt",
+				"update": {"outcome": "installed"}
+			}
+		]
+	}`, output.String())
+}
@@ -0,0 +1,230 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+
+	"github.com/Gelio/go-global-update/internal/config"
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+	"github.com/Gelio/go-global-update/internal/gocli"
+	"go.uber.org/zap"
+)
+
+type PathAuditOptions struct {
+	// PATH is the list of directories to audit, in the format of the PATH
+	// environment variable.
+	PATH string
+	// Config contains per-binary settings from the config file.
+	Config config.Config
+	// Dirs are additional directories with go binaries, scanned after GOBIN
+	// and GOPATH.
+	Dirs []string
+}
+
+type PathAuditFindingKind string
+
+const (
+	// PathAuditShadowed means a different executable with the same name is
+	// found in PATH before the binary.
+	PathAuditShadowed PathAuditFindingKind = "shadowed"
+	// PathAuditOutdated means a go binary outside of the directories with go
+	// binaries has a newer version.
+	PathAuditOutdated PathAuditFindingKind = "outdated"
+	// PathAuditUnreachable means the binary cannot be run using its name,
+	// because its directory is not in PATH.
+	PathAuditUnreachable PathAuditFindingKind = "unreachable"
+)
+
+type PathAuditFinding struct {
+	Kind PathAuditFindingKind
+	// Binary is the outdated binary for PathAuditOutdated findings, and
+	// a binary from GOBIN, GOPATH, or Dirs otherwise.
+	Binary gobinaries.GoBinary
+	// ShadowedByPath is the path of the executable that is run instead of
+	// Binary. It is only set for PathAuditShadowed findings.
+	ShadowedByPath string
+	// ShadowedByVersion is the version of the executable that is run instead
+	// of Binary. It is empty if the executable is not a go binary.
+	ShadowedByVersion string
+}
+
+// AuditPath looks for problems with go binaries in PATH: binaries from GOBIN,
+// GOPATH, and options.Dirs that are shadowed by other executables or are not
+// in PATH at all, and outdated go binaries in other PATH directories.
+//
+// The findings are reported as a PathAuditedEvent. Nothing is changed.
+func AuditPath(
+	ctx context.Context,
+	logger *zap.Logger,
+	options PathAuditOptions,
+	reporter Reporter,
+	cmdRunner gocli.GoCmdRunner,
+	lister gobinaries.DirectoryLister,
+	buildInfoReader gobinaries.BuildInfoReader,
+	fs FilesystemUtils,
+) error {
+	env, err := findGoEnvironment(ctx, logger, cmdRunner, options.Dirs, fs)
+	if err != nil {
+		return err
+	}
+
+	hermeticCmdRunner := gocli.NewHermeticCmdRunner(cmdRunner, env.gobin())
+	introspecter := gobinaries.NewIntrospecter(&hermeticCmdRunner, buildInfoReader, env.GOEXE, logger)
+	introspecter.SetOverrides(getOverrides(options.Config))
+
+	binaryPaths, _, err := resolveBinaryPaths(nil, lister, env)
+	if err != nil {
+		return err
+	}
+	// NOTE: the latest versions of binaries in the directories with go
+	// binaries are not needed. They are checked when updating them.
+	binaryResults := gobinaries.ReadBinaries(ctx, &introspecter, binaryPaths)
+
+	resolvedBinaryPaths := make(map[string]bool, len(binaryPaths))
+	for _, binaryPath := range binaryPaths {
+		resolvedBinaryPaths[resolvePath(fs, binaryPath)] = true
+	}
+
+	pathDirs := splitPATH(options.PATH)
+	pathEntries := make(map[string][]string, len(pathDirs))
+	var otherPaths []string
+	for _, dir := range pathDirs {
+		entries, err := lister.ListDirectoryEntries(dir)
+		if err != nil {
+			logger.Debug("skipping PATH directory", zap.String("dir", dir), zap.Error(err))
+			continue
+		}
+		pathEntries[dir] = entries
+
+		if containsString(env.binDirs, dir) {
+			continue
+		}
+		for _, entry := range entries {
+			otherPath := filepath.Join(dir, entry)
+			// NOTE: symlinks to binaries from the directories with go binaries
+			// are the same binaries.
+			if !resolvedBinaryPaths[resolvePath(fs, otherPath)] && isExecutable(fs, otherPath) {
+				otherPaths = append(otherPaths, otherPath)
+			}
+		}
+	}
+
+	// NOTE: most executables in PATH are not go binaries. They cannot be
+	// introspected and are skipped.
+	otherResults := gobinaries.IntrospectBinaries(ctx, &introspecter, otherPaths)
+	// NOTE: binaries can also be shadowed by binaries from other directories
+	// with go binaries, for example from another GOPATH entry.
+	versions := make(map[string]string, len(binaryResults)+len(otherResults))
+	for _, result := range append(binaryResults, otherResults...) {
+		if result.Error == nil {
+			versions[result.Binary.Path] = result.Binary.Version
+		}
+	}
+
+	var findings []PathAuditFinding
+	for _, result := range binaryResults {
+		if result.Error != nil {
+			continue
+		}
+		binary := result.Binary
+
+		foundPath, found := lookPath(fs, binary.Name, env.GOEXE, pathDirs, pathEntries)
+		switch {
+		case !found:
+			findings = append(findings, PathAuditFinding{Kind: PathAuditUnreachable, Binary: binary})
+		case resolvePath(fs, foundPath) != resolvePath(fs, binary.Path):
+			findings = append(findings, PathAuditFinding{
+				Kind:              PathAuditShadowed,
+				Binary:            binary,
+				ShadowedByPath:    foundPath,
+				ShadowedByVersion: versions[foundPath],
+			})
+		}
+	}
+
+	for _, result := range otherResults {
+		// NOTE: the latest version is unknown for binaries without a module,
+		// for example the go toolchain binaries.
+		if result.Error != nil || result.Binary.LatestVersion == "" {
+			continue
+		}
+		if !result.Binary.Held && result.Binary.UpgradePossible() {
+			findings = append(findings, PathAuditFinding{Kind: PathAuditOutdated, Binary: result.Binary})
+		}
+	}
+
+	reporter.Report(PathAuditedEvent{Findings: findings})
+	reporter.Report(SummaryEvent{Interrupted: ctx.Err() != nil})
+
+	if err := interruptedError(ctx); err != nil {
+		return err
+	}
+	if len(findings) > 0 {
+		return fmt.Errorf("found %d problem(s) with go binaries in PATH", len(findings))
+	}
+
+	return nil
+}
+
+// splitPATH returns the absolute paths of the directories in PATH, without
+// duplicates.
+func splitPATH(path string) []string {
+	var dirs []string
+	for _, dir := range filepath.SplitList(path) {
+		// NOTE: an empty entry means the current directory, which is not
+		// interesting for the audit.
+		if dir == "" {
+			continue
+		}
+		dir, err := filepath.Abs(dir)
+		if err == nil && !containsString(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs
+}
+
+// lookPath returns the path of the executable that is run for the binary
+// name, similarly to exec.LookPath. Entries that are not executable files are
+// skipped.
+func lookPath(fs FilesystemUtils, binaryName, goExe string, pathDirs []string, pathEntries map[string][]string) (string, bool) {
+	for _, dir := range pathDirs {
+		for _, entry := range pathEntries[dir] {
+			if entry != binaryName+goExe {
+				continue
+			}
+			if path := filepath.Join(dir, entry); isExecutable(fs, path) {
+				return path, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// isExecutable determines whether the path is a regular file that can be run.
+// Symlinks are followed.
+func isExecutable(fs FilesystemUtils, path string) bool {
+	info, err := fs.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+
+	// NOTE: there are no exec bits on Windows. Executables are recognized by
+	// their extension instead.
+	return runtime.GOOS == "windows" || info.Mode().Perm()&0o111 != 0
+}
+
+// resolvePath evaluates symlinks in the path. If that fails, the path is
+// returned unchanged.
+func resolvePath(fs FilesystemUtils, path string) string {
+	resolvedPath, err := fs.EvalSymlinks(path)
+	if err != nil {
+		return path
+	}
+
+	return resolvedPath
+}
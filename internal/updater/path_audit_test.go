@@ -0,0 +1,142 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gelio/go-global-update/internal/colors"
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+	"github.com/Gelio/go-global-update/internal/gobinariestest"
+	"github.com/Gelio/go-global-update/internal/gocli"
+	"github.com/Gelio/go-global-update/internal/goclitest"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestAuditPath(t *testing.T) {
+	otherBinDir := filepath.Join("/home", "test", "other", "bin")
+	systemBinDir := filepath.Join("/usr", "local", "bin")
+	gofumptMockBinary := gobinariestest.GetGofumptMockBinary()
+	shfmtMockBinary := gobinariestest.GetShfmtMockBinary()
+	goimportsMockBinary := gobinariestest.GetGoimportsMockBinary()
+	oldGofumptModuleInfo := strings.ReplaceAll(gofumptMockBinary.ModuleInfo, "v0.3.0", "v0.2.0")
+
+	logger := zap.NewNop()
+	options := PathAuditOptions{
+		PATH: strings.Join([]string{systemBinDir, gobinariestest.GOBIN}, string(filepath.ListSeparator)),
+	}
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		EntriesByDir: map[string][]string{
+			gobinariestest.GOBIN: {gofumptMockBinary.Binary.Name, shfmtMockBinary.Binary.Name},
+			otherBinDir:          {goimportsMockBinary.Binary.Name},
+			systemBinDir:         {gofumptMockBinary.Binary.Name, shfmtMockBinary.Binary.Name, "ls"},
+		},
+	}
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			goclitest.GetGoEnvMockResponse(gocli.GoEnv{
+				GOPATH: strings.Join([]string{filepath.Dir(gobinariestest.GOBIN), filepath.Dir(otherBinDir)}, string(filepath.ListSeparator)),
+			}),
+			gobinariestest.GetModuleInfoMockResponse(gofumptMockBinary),
+			gobinariestest.GetModuleInfoMockResponse(shfmtMockBinary),
+			goclitest.GetModuleInfoMockResponse(otherBinDir, goimportsMockBinary.Binary.Name, goimportsMockBinary.ModuleInfo),
+			goclitest.GetModuleInfoMockResponse(systemBinDir, gofumptMockBinary.Binary.Name, oldGofumptModuleInfo),
+			gobinariestest.GetLatestVersionMockResponse(gofumptMockBinary.Binary),
+		},
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := mockFilesystemUtils{
+		symlinks: map[string]string{
+			filepath.Join(systemBinDir, shfmtMockBinary.Binary.Name): shfmtMockBinary.Binary.Path,
+		},
+	}
+	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
+
+	err := AuditPath(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, fsutils)
+
+	assert.EqualError(t, err, "found 3 problem(s) with go binaries in PATH")
+	assert.Equal(t, strings.TrimSpace(`
+Binary         Path                                Problem
+gofumpt        /home/test/go/bin/gofumpt           shadowed by /usr/local/bin/gofumpt (v0.2.0 instead of v0.3.0)
+goimports      /home/test/other/bin/goimports      not reachable via PATH
+gofumpt        /usr/local/bin/gofumpt              outdated (v0.2.0, latest: v0.3.0)
+`), strings.TrimSpace(output.String()))
+}
+
+func TestAuditPathWithoutProblems(t *testing.T) {
+	shfmtMockBinary := gobinariestest.GetShfmtMockBinary()
+
+	logger := zap.NewNop()
+	options := PathAuditOptions{PATH: gobinariestest.GOBIN}
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		Entries: []string{shfmtMockBinary.Binary.Name},
+	}
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinMockResponse(),
+			gobinariestest.GetModuleInfoMockResponse(shfmtMockBinary),
+		},
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
+
+	err := AuditPath(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, mockFilesystemUtils{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "No problems with go binaries in PATH", strings.TrimSpace(output.String()))
+}
+
+func TestAuditPathSkipsEntriesThatAreNotExecutable(t *testing.T) {
+	systemBinDir := filepath.Join("/usr", "local", "bin")
+	gofumptMockBinary := gobinariestest.GetGofumptMockBinary()
+	shfmtMockBinary := gobinariestest.GetShfmtMockBinary()
+
+	logger := zap.NewNop()
+	options := PathAuditOptions{
+		PATH: strings.Join([]string{systemBinDir, gobinariestest.GOBIN}, string(filepath.ListSeparator)),
+	}
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		EntriesByDir: map[string][]string{
+			gobinariestest.GOBIN: {gofumptMockBinary.Binary.Name, shfmtMockBinary.Binary.Name},
+			systemBinDir:         {gofumptMockBinary.Binary.Name, shfmtMockBinary.Binary.Name},
+		},
+	}
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinMockResponse(),
+			gobinariestest.GetModuleInfoMockResponse(gofumptMockBinary),
+			gobinariestest.GetModuleInfoMockResponse(shfmtMockBinary),
+		},
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := mockFilesystemUtils{
+		modes: map[string]os.FileMode{
+			filepath.Join(systemBinDir, gofumptMockBinary.Binary.Name): 0o644,
+			filepath.Join(systemBinDir, shfmtMockBinary.Binary.Name):   os.ModeDir | 0o755,
+		},
+	}
+	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
+
+	err := AuditPath(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, fsutils)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "No problems with go binaries in PATH", strings.TrimSpace(output.String()))
+	for _, call := range cmdRunner.Calls() {
+		for _, arg := range call {
+			assert.NotContains(t, arg, systemBinDir, "entries that are not executable should not be introspected")
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package updater
+
+import (
+	"fmt"
+	"io"
+	"runtime/debug"
+
+	"github.com/Gelio/go-global-update/internal/colors"
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+)
+
+// Reporter receives events about the progress of updating binaries.
+//
+// The updater only decides what to do with the binaries. Presenting the
+// results is up to the Reporter.
+type Reporter interface {
+	Report(event Event)
+}
+
+// bufferedReporter collects events to report them later.
+type bufferedReporter struct {
+	events []Event
+}
+
+func (r *bufferedReporter) Report(event Event) {
+	r.events = append(r.events, event)
+}
+
+// replay reports the collected events to the reporter.
+func (r *bufferedReporter) replay(reporter Reporter) {
+	for _, event := range r.events {
+		reporter.Report(event)
+	}
+}
+
+// Event is one of the *Event types in this package.
+type Event interface {
+	isEvent()
+}
+
+// IntrospectedEvent is reported once all binaries have been introspected.
+type IntrospectedEvent struct {
+	Results []gobinaries.IntrospectionResult
+}
+
+// SkippedFromSourceEvent is reported when a binary should be updated, but it
+// was built from source and thus cannot be updated.
+type SkippedFromSourceEvent struct {
+	Binary gobinaries.GoBinary
+}
+
+// SkippedSymlinkEvent is reported when a binary should be updated, but it is
+// a symlink and symlinks are configured to be skipped.
+type SkippedSymlinkEvent struct {
+	Binary gobinaries.GoBinary
+}
+
+// InstallStartedEvent is reported right before a binary is installed.
+//
+// The Version of the Binary is empty if it is not installed yet, for example
+// when importing a manifest.
+type InstallStartedEvent struct {
+	Binary gobinaries.GoBinary
+	// BuildTags are the build tags used to install the binary.
+	BuildTags []string
+}
+
+// InstallFinishedEvent is reported after a binary was installed.
+type InstallFinishedEvent struct {
+	Binary gobinaries.GoBinary
+	// Output is the output of `go install`.
+	Output string
+	// Error is nil if the binary was installed successfully.
+	Error error
+	// DroppedSettings are the build settings of the binary that could not be
+	// replicated when installing it, for example `-ldflags` with `-X` stamps.
+	DroppedSettings []debug.BuildSetting
+	// SymlinkTarget is the new target of the symlink if the binary is
+	// a symlink that was pointed to a newly installed file.
+	SymlinkTarget string
+}
+
+// SmokeCheckFailedEvent is reported when the updated binary failed the smoke
+// check configured in the config file.
+type SmokeCheckFailedEvent struct {
+	Binary gobinaries.GoBinary
+	// Args are the arguments the binary was run with.
+	Args   []string
+	Output string
+	Error  error
+	// RolledBack is true if the binary was restored from a backup.
+	RolledBack    bool
+	RollbackError error
+}
+
+// ProblemDetectedEvent is reported when a common update problem is detected
+// for a binary.
+type ProblemDetectedEvent struct {
+	Binary  gobinaries.GoBinary
+	Problem CommonUpdateProblem
+}
+
+// SyncPlannedEvent is reported once the plan of syncing GOBIN with a manifest
+// is ready, before any binary is changed.
+type SyncPlannedEvent struct {
+	Steps []SyncStep
+}
+
+// RemovedEvent is reported after a binary was removed.
+type RemovedEvent struct {
+	Binary gobinaries.GoBinary
+	// Error is nil if the binary was removed successfully.
+	Error error
+}
+
+// PathAuditedEvent is reported once PATH has been audited.
+type PathAuditedEvent struct {
+	Findings []PathAuditFinding
+}
+
+// SummaryEvent is the last event reported during a run.
+type SummaryEvent struct {
+	DryRun      bool
+	Installed   int
+	Upgraded    int
+	Downgraded  int
+	Reinstalled int
+	Removed     int
+	Failed      int
+	// Mismatched is the number of binaries whose built version, module, or
+	// build tags do not match the plan.
+	Mismatched int
+	Skipped    int
+	// Canceled is the number of binaries that were not installed because the
+	// run was interrupted or timed out.
+	Canceled int
+	// Interrupted is set when the run was stopped early.
+	Interrupted bool
+}
+
+func (IntrospectedEvent) isEvent()      {}
+func (SkippedFromSourceEvent) isEvent() {}
+func (SkippedSymlinkEvent) isEvent()    {}
+func (InstallStartedEvent) isEvent()    {}
+func (InstallFinishedEvent) isEvent()   {}
+func (SmokeCheckFailedEvent) isEvent()  {}
+func (ProblemDetectedEvent) isEvent()   {}
+func (SyncPlannedEvent) isEvent()       {}
+func (RemovedEvent) isEvent()           {}
+func (PathAuditedEvent) isEvent()       {}
+func (SummaryEvent) isEvent()           {}
+
+type OutputFormat string
+
+const (
+	// OutputFormatText is a human-readable output with a table and colors.
+	OutputFormatText OutputFormat = "text"
+	// OutputFormatJSON is a single machine-readable JSON document per run.
+	OutputFormatJSON OutputFormat = "json"
+)
+
+func ParseOutputFormat(format string) (OutputFormat, error) {
+	switch outputFormat := OutputFormat(format); outputFormat {
+	case OutputFormatText, OutputFormatJSON:
+		return outputFormat, nil
+	default:
+		return "", fmt.Errorf("unknown output format %q (expected %q or %q)", format, OutputFormatText, OutputFormatJSON)
+	}
+}
+
+// NewReporter returns the built-in Reporter for the output format.
+func NewReporter(
+	format OutputFormat,
+	out io.Writer,
+	colorsFactory *colors.DecoratorFactory,
+	verbose bool,
+) Reporter {
+	if format == OutputFormatJSON {
+		reporter := NewJSONReporter(out)
+		return &reporter
+	}
+
+	reporter := NewTextReporter(out, colorsFactory, verbose)
+	return &reporter
+}
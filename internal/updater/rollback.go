@@ -0,0 +1,47 @@
+package updater
+
+import (
+	"context"
+
+	"github.com/Gelio/go-global-update/internal/backup"
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+	"github.com/Gelio/go-global-update/internal/gocli"
+	"go.uber.org/zap"
+)
+
+// RollbackBinary restores a backup of the binary.
+//
+// The binary is looked up in GOBIN, GOPATH, and dirs. If it is not found, the
+// backup is restored into GOBIN. If the version is empty, the
+// newest backup is restored. If the binary is a symlink, the backup is
+// restored into the file it points to and the symlink is kept.
+func RollbackBinary(
+	ctx context.Context,
+	logger *zap.Logger,
+	binaryName string,
+	version string,
+	dirs []string,
+	backups *backup.Store,
+	cmdRunner gocli.GoCmdRunner,
+	lister gobinaries.DirectoryLister,
+	fs FilesystemUtils,
+) (backup.Entry, error) {
+	env, err := findGoEnvironment(ctx, logger, cmdRunner, dirs, fs)
+	if err != nil {
+		return backup.Entry{}, err
+	}
+
+	binaryPaths, _, err := resolveBinaryPaths([]string{binaryName}, lister, env)
+	if err != nil {
+		return backup.Entry{}, err
+	}
+
+	entry, err := backups.Find(binaryPaths[0], binaryName, version)
+	if err != nil {
+		return backup.Entry{}, err
+	}
+
+	logger.Debug("restoring backup", zap.String("binary", binaryName), zap.String("backup", entry.Path))
+
+	return entry, backups.Restore(entry, resolvePath(fs, binaryPaths[0]))
+}
@@ -0,0 +1,135 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gelio/go-global-update/internal/backup"
+	"github.com/Gelio/go-global-update/internal/colors"
+	"github.com/Gelio/go-global-update/internal/config"
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+	"github.com/Gelio/go-global-update/internal/gobinariestest"
+	"github.com/Gelio/go-global-update/internal/gocli"
+	"github.com/Gelio/go-global-update/internal/goclitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// brokenBinarySmokeChecker simulates a broken release: it overwrites the
+// binary and fails.
+type brokenBinarySmokeChecker struct{}
+
+func (_ *brokenBinarySmokeChecker) Check(_ context.Context, binaryPath string, _ []string) (string, error) {
+	if err := os.WriteFile(binaryPath, []byte("broken"), 0o755); err != nil {
+		return "", err
+	}
+
+	return "segmentation fault", errors.New("exit status 139")
+}
+
+func TestRollbackWhenSmokeCheckFails(t *testing.T) {
+	gobin := t.TempDir()
+	shfmtMockBinary := gobinariestest.GetShfmtMockBinary()
+	shfmtMockBinary.Binary.LatestVersion = "v3.4.3"
+	shfmtPath := filepath.Join(gobin, shfmtMockBinary.Binary.Name)
+	shfmtMockBinary.Binary.Path = shfmtPath
+	require.Nil(t, os.WriteFile(shfmtPath, []byte("v3.4.2"), 0o755))
+
+	logger := zap.NewNop()
+	backups := backup.NewStore(t.TempDir(), 3)
+	options := Options{
+		Backups: &backups,
+		Config: config.Config{
+			Binaries: map[string]config.Binary{
+				"shfmt": {Check: []string{"--version"}},
+			},
+		},
+	}
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		Entries: []string{shfmtMockBinary.Binary.Name},
+	}
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			goclitest.GetGoEnvMockResponse(gocli.GoEnv{GOBIN: gobin}),
+			gobinariestest.GetLatestVersionMockResponse(shfmtMockBinary.Binary),
+			goclitest.GetModuleInfoMockResponse(gobin, shfmtMockBinary.Binary.Name, shfmtMockBinary.ModuleInfo),
+			updateMockResponse(shfmtMockBinary.Binary, "", nil),
+			stagedBuildInfoMockResponse(shfmtMockBinary.Binary),
+		},
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := mockFilesystemUtils{}
+	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
+
+	err := UpdateBinaries(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, fsutils, &brokenBinarySmokeChecker{})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, strings.TrimSpace(`
+Binary      Current version      Status
+shfmt       v3.4.2               can upgrade to v3.4.3 (patch)
+
+Upgrading shfmt to v3.4.3 ... ✅
+    Smoke check "shfmt --version" failed: exit status 139
+segmentation fault
+    Rolled back to v3.4.2
+
+Could not install 1 package(s)
+`), strings.TrimSpace(output.String()))
+
+	contents, err := os.ReadFile(shfmtPath)
+	require.Nil(t, err)
+	assert.Equal(t, "v3.4.2", string(contents))
+
+	// NOTE: the backup is kept, so it can be restored again later
+	require.Nil(t, os.WriteFile(shfmtPath, []byte("v3.4.3"), 0o755))
+	entry, err := RollbackBinary(context.Background(), logger, "shfmt", "v3.4.2", nil, &backups, &cmdRunner, &lister, fsutils)
+	require.Nil(t, err)
+	assert.Equal(t, "v3.4.2", entry.Version)
+
+	contents, err = os.ReadFile(shfmtPath)
+	require.Nil(t, err)
+	assert.Equal(t, "v3.4.2", string(contents))
+}
+
+func TestRollbackSymlinkedBinary(t *testing.T) {
+	gobin := t.TempDir()
+	symlinkPath := filepath.Join(gobin, "shfmt")
+	targetPath := filepath.Join(gobin, "shfmt-v3.4.3")
+	require.Nil(t, os.WriteFile(targetPath, []byte("v3.4.2"), 0o755))
+	require.Nil(t, os.Symlink("shfmt-v3.4.3", symlinkPath))
+
+	logger := zap.NewNop()
+	backups := backup.NewStore(t.TempDir(), 3)
+	_, err := backups.Backup(symlinkPath, "shfmt", "v3.4.2")
+	require.Nil(t, err)
+	require.Nil(t, os.WriteFile(targetPath, []byte("v3.4.3"), 0o755))
+
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		Entries: []string{"shfmt", "shfmt-v3.4.3"},
+	}
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			goclitest.GetGoEnvMockResponse(gocli.GoEnv{GOBIN: gobin}),
+		},
+	}
+
+	_, err = RollbackBinary(context.Background(), logger, "shfmt", "", nil, &backups, &cmdRunner, &lister, &Filesystem{})
+	require.Nil(t, err)
+
+	destination, err := os.Readlink(symlinkPath)
+	require.Nil(t, err, "the symlink should be kept")
+	assert.Equal(t, "shfmt-v3.4.3", destination)
+
+	contents, err := os.ReadFile(targetPath)
+	require.Nil(t, err)
+	assert.Equal(t, "v3.4.2", string(contents))
+}
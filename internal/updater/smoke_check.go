@@ -0,0 +1,39 @@
+package updater
+
+import (
+	"context"
+	"os/exec"
+	"time"
+)
+
+// defaultSmokeCheckTimeout is how long a smoke check can run if
+// ExecSmokeChecker.Timeout is not set.
+const defaultSmokeCheckTimeout = 30 * time.Second
+
+// SmokeChecker runs a binary after it is updated to check whether it works.
+type SmokeChecker interface {
+	Check(ctx context.Context, binaryPath string, args []string) (string, error)
+}
+
+type ExecSmokeChecker struct {
+	// Timeout is how long the binary can run before it is killed and the
+	// check fails. If it is not positive, defaultSmokeCheckTimeout is used.
+	Timeout time.Duration
+}
+
+func (c *ExecSmokeChecker) Check(ctx context.Context, binaryPath string, args []string) (string, error) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultSmokeCheckTimeout
+	}
+	// NOTE: a binary that hangs must not block the update
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, binaryPath, args...).CombinedOutput()
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+
+	return string(output), err
+}
@@ -0,0 +1,47 @@
+package updater
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSmokeCheckTimesOut(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hanging binary is a shell script")
+	}
+
+	binaryPath := filepath.Join(t.TempDir(), "hanging")
+	require.Nil(t, os.WriteFile(binaryPath, []byte("#!/bin/sh\nexec sleep 10\n"), 0o755))
+	smokeChecker := ExecSmokeChecker{Timeout: 100 * time.Millisecond}
+
+	start := time.Now()
+	_, err := smokeChecker.Check(context.Background(), binaryPath, []string{"--version"})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestSmokeCheckStopsWhenInterrupted(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hanging binary is a shell script")
+	}
+
+	binaryPath := filepath.Join(t.TempDir(), "hanging")
+	require.Nil(t, os.WriteFile(binaryPath, []byte("#!/bin/sh\nexec sleep 10\n"), 0o755))
+	smokeChecker := ExecSmokeChecker{}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := smokeChecker.Check(ctx, binaryPath, []string{"--version"})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
@@ -0,0 +1,125 @@
+package updater
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+)
+
+// SymlinkMode determines how binaries that are symlinks are updated.
+type SymlinkMode string
+
+const (
+	// SymlinkModeTarget updates the file the symlink points to in place.
+	SymlinkModeTarget SymlinkMode = "target"
+	// SymlinkModeSkip does not update symlinks.
+	SymlinkModeSkip SymlinkMode = "skip"
+	// SymlinkModeRetarget installs the new version into a new file next to
+	// the current target (for example `gopls-v0.16.0`) and points the symlink
+	// to it. The previous target is kept.
+	SymlinkModeRetarget SymlinkMode = "retarget"
+)
+
+func ParseSymlinkMode(mode string) (SymlinkMode, error) {
+	switch symlinkMode := SymlinkMode(mode); symlinkMode {
+	case SymlinkModeTarget, SymlinkModeSkip, SymlinkModeRetarget:
+		return symlinkMode, nil
+	default:
+		return "", fmt.Errorf("unknown symlink mode %q (expected %q, %q, or %q)",
+			mode, SymlinkModeTarget, SymlinkModeSkip, SymlinkModeRetarget)
+	}
+}
+
+// getSymlinkMode returns the symlink mode of the binary. The mode from the
+// config file takes precedence over defaultMode.
+func getSymlinkMode(binaryConfigMode string, defaultMode SymlinkMode) SymlinkMode {
+	if binaryConfigMode != "" {
+		return SymlinkMode(binaryConfigMode)
+	}
+	if defaultMode != "" {
+		return defaultMode
+	}
+
+	return SymlinkModeTarget
+}
+
+// resolveSymlinks sets the SymlinkTarget of the binaries that are symlinks.
+func resolveSymlinks(introspectionResults []gobinaries.IntrospectionResult, fs FilesystemUtils) {
+	for i := range introspectionResults {
+		binary := &introspectionResults[i].Binary
+		destination, err := fs.Readlink(binary.Path)
+		if err != nil {
+			continue
+		}
+
+		target, err := fs.EvalSymlinks(binary.Path)
+		if err != nil {
+			// NOTE: the symlink is broken
+			target = destination
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(binary.Path), target)
+			}
+		}
+		binary.SymlinkTarget = target
+	}
+}
+
+// symlinkTargets returns the paths of the binaries that are targets of other
+// binaries. They are updated through the symlinks.
+func symlinkTargets(introspectionResults []gobinaries.IntrospectionResult, fs FilesystemUtils) map[string]bool {
+	resolvedTargets := make(map[string]bool)
+	for _, result := range introspectionResults {
+		if result.Binary.SymlinkTarget != "" {
+			resolvedTargets[result.Binary.SymlinkTarget] = true
+		}
+	}
+
+	// NOTE: the targets are resolved, while the binaries can be in a symlinked
+	// directory (for example when GOBIN is a symlink). The file names are not
+	// resolved, so symlinks are not mistaken for their targets.
+	targets := make(map[string]bool)
+	for _, result := range introspectionResults {
+		path := result.Binary.Path
+		if resolvedTargets[filepath.Join(resolvePath(fs, filepath.Dir(path)), filepath.Base(path))] {
+			targets[path] = true
+		}
+	}
+
+	return targets
+}
+
+// retargetedPath returns the path of the versioned file the new version of the
+// binary is installed into in SymlinkModeRetarget, next to the current target
+// of the symlink.
+func retargetedPath(binary gobinaries.GoBinary, goExe string) string {
+	version := binary.LatestVersion
+	if version == "" {
+		version = binary.Version
+	}
+
+	return filepath.Join(filepath.Dir(binary.SymlinkTarget), fmt.Sprintf("%s-%s%s", binary.Name, version, goExe))
+}
+
+// retargetSymlink atomically changes the symlink to point to newTarget.
+func retargetSymlink(symlinkPath, newTarget string, fs FilesystemUtils) error {
+	destination := newTarget
+	// NOTE: keep relative symlinks relative
+	if oldDestination, err := fs.Readlink(symlinkPath); err == nil && !filepath.IsAbs(oldDestination) {
+		if relativeDestination, err := filepath.Rel(filepath.Dir(symlinkPath), newTarget); err == nil {
+			destination = relativeDestination
+		}
+	}
+
+	temporaryPath := symlinkPath + ".retarget"
+	_ = fs.Remove(temporaryPath)
+	if err := fs.Symlink(destination, temporaryPath); err != nil {
+		return fmt.Errorf("could not create a symlink to %s: %w", newTarget, err)
+	}
+	if err := fs.Rename(temporaryPath, symlinkPath); err != nil {
+		_ = fs.Remove(temporaryPath)
+		return fmt.Errorf("could not point %s to %s: %w", symlinkPath, newTarget, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gelio/go-global-update/internal/colors"
+	"github.com/Gelio/go-global-update/internal/config"
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+	"github.com/Gelio/go-global-update/internal/gobinariestest"
+	"github.com/Gelio/go-global-update/internal/goclitest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// movingFilesystemUtils records the renames and the created symlinks. Its
+// symlinks are relative.
+type movingFilesystemUtils struct {
+	mockFilesystemUtils
+	operations []string
+}
+
+func (fs *movingFilesystemUtils) Readlink(path string) (string, error) {
+	target, err := fs.mockFilesystemUtils.Readlink(path)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Rel(filepath.Dir(path), target)
+}
+
+func (fs *movingFilesystemUtils) Rename(oldPath, newPath string) error {
+	fs.operations = append(fs.operations, fmt.Sprintf("rename %s %s", oldPath, newPath))
+	return nil
+}
+
+func (fs *movingFilesystemUtils) Symlink(oldName, newName string) error {
+	fs.operations = append(fs.operations, fmt.Sprintf("symlink %s %s", oldName, newName))
+	return nil
+}
+
+func TestUpdateSymlinkedBinaries(t *testing.T) {
+	gofumptMockBinary := gobinariestest.GetGofumptMockBinary()
+	gofumptMockBinary.Binary.LatestVersion = "v0.4.0"
+	symlinkPath := gofumptMockBinary.Binary.Path
+	targetPath := filepath.Join(gobinariestest.GOBIN, "gofumpt-v0.3.0")
+	stagingDir := filepath.Join(gobinariestest.GOBIN, ".go-global-update-0")
+
+	testCases := []struct {
+		name               string
+		mode               SymlinkMode
+		expectedOperations []string
+		expectedOutput     string
+	}{
+		{
+			name: "target",
+			mode: SymlinkModeTarget,
+			expectedOperations: []string{
+				fmt.Sprintf("rename %s %s", filepath.Join(stagingDir, "gofumpt"), targetPath),
+			},
+			expectedOutput: `
+Binary                         Current version      Status
+gofumpt -> gofumpt-v0.3.0      v0.3.0               can upgrade to v0.4.0 (minor)
+
+Upgrading gofumpt to v0.4.0 ... ✅
+`,
+		},
+		{
+			name: "retarget",
+			mode: SymlinkModeRetarget,
+			expectedOperations: []string{
+				fmt.Sprintf("rename %s %s", filepath.Join(stagingDir, "gofumpt"), filepath.Join(gobinariestest.GOBIN, "gofumpt-v0.4.0")),
+				fmt.Sprintf("symlink %s %s", "gofumpt-v0.4.0", symlinkPath+".retarget"),
+				fmt.Sprintf("rename %s %s", symlinkPath+".retarget", symlinkPath),
+			},
+			expectedOutput: `
+Binary                         Current version      Status
+gofumpt -> gofumpt-v0.3.0      v0.3.0               can upgrade to v0.4.0 (minor)
+
+Upgrading gofumpt to v0.4.0 ... ✅
+    Linked gofumpt -> gofumpt-v0.4.0
+`,
+		},
+		{
+			name: "skip",
+			mode: SymlinkModeSkip,
+			expectedOutput: `
+Binary                         Current version      Status
+gofumpt -> gofumpt-v0.3.0      v0.3.0               can upgrade to v0.4.0 (minor)
+
+Skipping upgrading gofumpt
+    The binary is a symlink to ` + targetPath + `.
+    Use "--symlinks=target" or "--symlinks=retarget" to update it.
+`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			logger := zap.NewNop()
+			options := Options{Symlinks: testCase.mode}
+			var output bytes.Buffer
+			lister := gobinariestest.TestSuccessDirectoryLister{
+				Entries: []string{gofumptMockBinary.Binary.Name},
+			}
+			responses := []goclitest.MockResponse{
+				gobinMockResponse(),
+				gobinariestest.GetLatestVersionMockResponse(gofumptMockBinary.Binary),
+				gobinariestest.GetModuleInfoMockResponse(gofumptMockBinary),
+			}
+			if testCase.mode != SymlinkModeSkip {
+				responses = append(responses, goclitest.MockResponse{
+					Args: []string{"install", "mvdan.cc/gofumpt@v0.4.0"},
+					Env:  []string{"GOWORK=off", "GOBIN=" + stagingDir},
+				}, stagedBuildInfoMockResponse(gofumptMockBinary.Binary))
+			}
+			cmdRunner := goclitest.TestGoCmdRunner{Responses: responses}
+
+			buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+			fsutils := movingFilesystemUtils{
+				mockFilesystemUtils: mockFilesystemUtils{symlinks: map[string]string{symlinkPath: targetPath}},
+			}
+			colorsFactory := colors.NewFactory(false)
+			reporter := NewTextReporter(&output, &colorsFactory, false)
+
+			err := UpdateBinaries(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, &fsutils, &mockSmokeChecker{})
+
+			assert.Nil(t, err)
+			assert.Equal(t, testCase.expectedOperations, fsutils.operations)
+			assert.Equal(t, strings.TrimSpace(testCase.expectedOutput), strings.TrimSpace(output.String()))
+		})
+	}
+}
+
+func TestSymlinkModeFromConfigTakesPrecedence(t *testing.T) {
+	binaryConfig := config.Binary{Symlinks: string(SymlinkModeSkip)}
+
+	assert.Equal(t, SymlinkModeSkip, getSymlinkMode(binaryConfig.Symlinks, SymlinkModeRetarget))
+	assert.Equal(t, SymlinkModeRetarget, getSymlinkMode("", SymlinkModeRetarget))
+	assert.Equal(t, SymlinkModeTarget, getSymlinkMode("", ""))
+}
+
+func TestSymlinkTargetsInSymlinkedDirectory(t *testing.T) {
+	realDir := t.TempDir()
+	// NOTE: GOBIN is a symlink to realDir
+	gobin := filepath.Join(t.TempDir(), "bin")
+	require.Nil(t, os.Symlink(realDir, gobin))
+	require.Nil(t, os.WriteFile(filepath.Join(realDir, "gopls-v0.15.0"), []byte("v0.15.0"), 0o755))
+	require.Nil(t, os.Symlink("gopls-v0.15.0", filepath.Join(realDir, "gopls")))
+
+	results := []gobinaries.IntrospectionResult{
+		{Binary: gobinaries.GoBinary{Name: "gopls", Path: filepath.Join(gobin, "gopls")}},
+		{Binary: gobinaries.GoBinary{Name: "gopls-v0.15.0", Path: filepath.Join(gobin, "gopls-v0.15.0")}},
+	}
+	fs := &Filesystem{}
+	resolveSymlinks(results, fs)
+
+	assert.Equal(t, map[string]bool{
+		filepath.Join(gobin, "gopls-v0.15.0"): true,
+	}, symlinkTargets(results, fs), "the target should only be updated through the symlink")
+}
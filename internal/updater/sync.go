@@ -0,0 +1,322 @@
+package updater
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/Gelio/go-global-update/internal/config"
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+	"github.com/Gelio/go-global-update/internal/gocli"
+	"github.com/Gelio/go-global-update/internal/manifest"
+	"go.uber.org/zap"
+)
+
+type SyncOptions struct {
+	DryRun bool
+	// Whether to remove binaries that are not listed in the manifest.
+	Prune bool
+	// Config contains per-binary settings from the config file.
+	Config config.Config
+	// Dirs are additional directories with go binaries, scanned after GOBIN
+	// and GOPATH.
+	Dirs []string
+	// Whether to hide entries that are not go binaries, for example shell
+	// scripts or directories.
+	IgnoreNonGo bool
+	// Symlinks determines how binaries that are symlinks are updated. The
+	// setting from the config file takes precedence.
+	Symlinks SymlinkMode
+}
+
+type SyncAction string
+
+const (
+	// SyncActionNone means the binary already matches the manifest.
+	SyncActionNone SyncAction = "none"
+	// SyncActionHold means the binary does not match the manifest, but it is
+	// held in the config file.
+	SyncActionHold SyncAction = "hold"
+	// SyncActionInstall means the binary is missing from GOBIN or it could not
+	// be introspected.
+	SyncActionInstall   SyncAction = "install"
+	SyncActionUpgrade   SyncAction = "upgrade"
+	SyncActionDowngrade SyncAction = "downgrade"
+	// SyncActionReinstall means the binary has to be reinstalled because its
+	// version cannot be compared with the manifest (for example because it was
+	// built from source) or it was installed from a different package.
+	SyncActionReinstall SyncAction = "reinstall"
+	// SyncActionRemove means the binary is not listed in the manifest and will
+	// be removed.
+	SyncActionRemove SyncAction = "remove"
+	// SyncActionKeep means the binary is not listed in the manifest, but it
+	// will not be removed.
+	SyncActionKeep SyncAction = "keep"
+)
+
+// SyncStep is a single step of making GOBIN match a manifest.
+type SyncStep struct {
+	Action SyncAction
+	// Binary is the binary in GOBIN. For steps that install a binary, its
+	// LatestVersion is the version from the manifest and its Version is empty
+	// if the binary is not installed yet.
+	Binary gobinaries.GoBinary
+	// Error is the reason why the binary in GOBIN could not be introspected.
+	Error error
+}
+
+func (s *SyncStep) installs() bool {
+	switch s.Action {
+	case SyncActionInstall, SyncActionUpgrade, SyncActionDowngrade, SyncActionReinstall:
+		return true
+	default:
+		return false
+	}
+}
+
+// SyncManifest makes the binaries in GOBIN match the manifest: it installs
+// missing binaries, upgrades or downgrades binaries to the versions from the
+// manifest, and optionally removes binaries that are not in the manifest.
+//
+// The plan is reported as a SyncPlannedEvent before anything is changed. In
+// dry-run mode, nothing is changed.
+func SyncManifest(
+	ctx context.Context,
+	logger *zap.Logger,
+	syncedManifest manifest.Manifest,
+	options SyncOptions,
+	reporter Reporter,
+	cmdRunner gocli.GoCmdRunner,
+	lister gobinaries.DirectoryLister,
+	buildInfoReader gobinaries.BuildInfoReader,
+	fs FilesystemUtils,
+) error {
+	env, err := findGoEnvironment(ctx, logger, cmdRunner, options.Dirs, fs)
+	if err != nil {
+		return err
+	}
+
+	hermeticCmdRunner := gocli.NewHermeticCmdRunner(cmdRunner, env.gobin())
+	goCLI := gocli.New(&hermeticCmdRunner)
+	introspecter := gobinaries.NewIntrospecter(&hermeticCmdRunner, buildInfoReader, env.GOEXE, logger)
+	introspecter.SetOverrides(getOverrides(options.Config))
+	binaryPaths, _, err := resolveBinaryPaths(nil, lister, env)
+	if err != nil {
+		return err
+	}
+
+	// NOTE: the versions from the manifest are the target versions, so there is
+	// no need to resolve the latest versions.
+	introspectionResults := gobinaries.ReadBinaries(ctx, &introspecter, binaryPaths)
+	resolveSymlinks(introspectionResults, fs)
+	if options.IgnoreNonGo {
+		introspectionResults = withoutNonGoEntries(introspectionResults)
+	}
+	steps := planSync(introspectionResults, syncedManifest, env, fs, options.Prune)
+	reporter.Report(SyncPlannedEvent{Steps: steps})
+
+	summary := SummaryEvent{DryRun: options.DryRun}
+	if !options.DryRun {
+		installer := newInstaller(&goCLI, buildInfoReader, env, fs)
+		syncBinaries(ctx, steps, &installer, reporter, options, env, fs, &summary)
+	}
+	summary.Interrupted = ctx.Err() != nil
+	reporter.Report(summary)
+
+	if err := interruptedError(ctx); err != nil {
+		return err
+	}
+
+	return failedError(summary)
+}
+
+// planSync compares the introspected binaries with the manifest.
+//
+// The binaries from the manifest are compared with the binaries in GOBIN, where
+// they are installed. Binaries with the same names in other directories are
+// not considered installed.
+//
+// The steps for binaries from the manifest come first, in the manifest order.
+// They are followed by the steps for other binaries.
+func planSync(
+	introspectionResults []gobinaries.IntrospectionResult,
+	syncedManifest manifest.Manifest,
+	env goEnvironment,
+	fs FilesystemUtils,
+	prune bool,
+) []SyncStep {
+	resultsByPath := make(map[string]gobinaries.IntrospectionResult, len(introspectionResults))
+	for _, result := range introspectionResults {
+		resultsByPath[result.Binary.Path] = result
+	}
+
+	var steps []SyncStep
+	// inManifest contains the paths of the binaries from the manifest.
+	inManifest := make(map[string]bool, len(syncedManifest.Binaries))
+	for _, manifestBinary := range syncedManifest.Binaries {
+		binaryPath := env.newBinaryPath(manifestBinary.Name)
+		inManifest[binaryPath] = true
+
+		result, installed := resultsByPath[binaryPath]
+		if !installed || result.Error != nil {
+			steps = append(steps, SyncStep{
+				Action: SyncActionInstall,
+				Binary: gobinaries.GoBinary{
+					Name:          manifestBinary.Name,
+					Path:          binaryPath,
+					PathURL:       manifestBinary.PathURL,
+					ModuleURL:     manifestBinary.Module,
+					LatestVersion: manifestBinary.Version,
+					VersionQuery:  manifestBinary.Version,
+					BuildTags:     manifestBinary.BuildTags,
+				},
+				Error: result.Error,
+			})
+			continue
+		}
+
+		binary := result.Binary
+		installedBuildTags := binary.BuildTags
+		binary.LatestVersion = manifestBinary.Version
+		binary.VersionQuery = manifestBinary.Version
+		binary.BuildTags = manifestBinary.BuildTags
+		if binary.PathURL != manifestBinary.PathURL {
+			binary.InstallPathURL = manifestBinary.PathURL
+			binary.InstallModuleURL = manifestBinary.Module
+		}
+
+		action := getSyncAction(binary, installedBuildTags)
+		if action != SyncActionNone && binary.Held {
+			action = SyncActionHold
+		}
+		steps = append(steps, SyncStep{Action: action, Binary: binary})
+	}
+
+	targets := symlinkTargets(introspectionResults, fs)
+	for _, result := range introspectionResults {
+		if inManifest[result.Binary.Path] {
+			continue
+		}
+
+		step := SyncStep{Action: SyncActionKeep, Binary: result.Binary, Error: result.Error}
+		if prune && canPrune(result, env, targets) {
+			step.Action = SyncActionRemove
+		}
+		steps = append(steps, step)
+	}
+
+	return steps
+}
+
+// canPrune determines whether a binary that is not in the manifest can be
+// removed.
+//
+// NOTE: only remove binaries in GOBIN that are known to be Go binaries. Other
+// files in GOBIN could have been put there on purpose, and binaries in other
+// directories are not managed by the manifest. Binaries built from source are
+// left out of manifests, because they cannot be reinstalled, so they are kept
+// too. Symlink targets are kept, so the symlinks are not broken.
+func canPrune(result gobinaries.IntrospectionResult, env goEnvironment, symlinkTargets map[string]bool) bool {
+	binary := result.Binary
+
+	return result.Error == nil &&
+		filepath.Dir(binary.Path) == env.gobin() &&
+		!binary.Held &&
+		!binary.BuiltFromSource() &&
+		!symlinkTargets[binary.Path]
+}
+
+// getSyncAction returns the action that makes the binary match the manifest.
+// The binary has the version and the build tags from the manifest, and
+// installedBuildTags are the build tags it was built with.
+func getSyncAction(binary gobinaries.GoBinary, installedBuildTags []string) SyncAction {
+	if binary.PathChanged() {
+		return SyncActionReinstall
+	}
+
+	switch binary.UpgradeState() {
+	case gobinaries.UpgradeStateEqual:
+		if formatBuildTags(installedBuildTags) != formatBuildTags(binary.BuildTags) {
+			return SyncActionReinstall
+		}
+		return SyncActionNone
+	case gobinaries.UpgradeStateNewerThanLatest:
+		return SyncActionDowngrade
+	case gobinaries.UpgradeStateIncomparable:
+		return SyncActionReinstall
+	default:
+		return SyncActionUpgrade
+	}
+}
+
+func syncBinaries(
+	ctx context.Context,
+	steps []SyncStep,
+	installer *installer,
+	reporter Reporter,
+	options SyncOptions,
+	env goEnvironment,
+	fs FilesystemUtils,
+	summary *SummaryEvent,
+) {
+	for _, step := range steps {
+		binary := step.Binary
+
+		if ctx.Err() != nil {
+			if step.installs() || step.Action == SyncActionRemove {
+				summary.Canceled++
+			}
+			continue
+		}
+
+		if step.Action == SyncActionRemove {
+			err := fs.Remove(binary.Path)
+			reporter.Report(RemovedEvent{Binary: binary, Error: err})
+			if err != nil {
+				summary.Failed++
+			} else {
+				summary.Removed++
+			}
+			continue
+		}
+
+		if !step.installs() {
+			continue
+		}
+
+		binaryConfig := options.Config.Binaries[binary.Name]
+		symlinkMode := getSymlinkMode(binaryConfig.Symlinks, options.Symlinks)
+		if binary.SymlinkTarget != "" && symlinkMode == SymlinkModeSkip {
+			reporter.Report(SkippedSymlinkEvent{Binary: binary})
+			summary.Skipped++
+			continue
+		}
+
+		installOptions, droppedSettings := getInstallOptions(binary, binaryConfig, env)
+		reporter.Report(InstallStartedEvent{Binary: binary, BuildTags: installOptions.BuildTags})
+		installOutput, newSymlinkTarget, err := installer.install(ctx, binary, installOptions, symlinkMode)
+		reporter.Report(InstallFinishedEvent{
+			Binary:          binary,
+			Output:          installOutput,
+			Error:           err,
+			DroppedSettings: droppedSettings,
+			SymlinkTarget:   newSymlinkTarget,
+		})
+
+		for _, problem := range FindCommonUpdateProblems(installOutput) {
+			reporter.Report(ProblemDetectedEvent{Binary: binary, Problem: problem})
+		}
+
+		switch {
+		case err != nil:
+			countFailed(summary, err)
+		case step.Action == SyncActionInstall:
+			summary.Installed++
+		case step.Action == SyncActionUpgrade:
+			summary.Upgraded++
+		case step.Action == SyncActionDowngrade:
+			summary.Downgraded++
+		default:
+			summary.Reinstalled++
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gelio/go-global-update/internal/colors"
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+	"github.com/Gelio/go-global-update/internal/gobinariestest"
+	"github.com/Gelio/go-global-update/internal/goclitest"
+	"github.com/Gelio/go-global-update/internal/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type recordingFilesystemUtils struct {
+	mockFilesystemUtils
+	removedPaths []string
+}
+
+func (fs *recordingFilesystemUtils) Remove(path string) error {
+	fs.removedPaths = append(fs.removedPaths, path)
+	return nil
+}
+
+func TestPlanSync(t *testing.T) {
+	shfmt := gobinariestest.GetShfmtMockBinary().Binary
+	gofumpt := gobinariestest.GetGofumptMockBinary().Binary
+	goimports := gobinariestest.GetGoimportsMockBinary().Binary
+	stringer := gobinariestest.GetStringerMockBinary().Binary
+	stringer.Held = true
+	builtFromSource := gobinariestest.GetGofumptMockBinary().Binary
+	builtFromSource.Name = "local-tool"
+	builtFromSource.Path = filepath.Join(gobinariestest.GOBIN, "local-tool")
+	builtFromSource.Version = "(devel)"
+
+	results := []gobinaries.IntrospectionResult{
+		{Binary: shfmt},
+		{Binary: gofumpt},
+		{Binary: goimports},
+		{Binary: stringer},
+		{Binary: builtFromSource},
+		{Binary: gobinaries.GoBinary{Name: "script", Path: filepath.Join(gobinariestest.GOBIN, "script")}, Error: errors.New("not a Go binary")},
+	}
+	syncedManifest := manifest.Manifest{
+		Binaries: []manifest.Binary{
+			{Name: "shfmt", PathURL: shfmt.PathURL, Module: shfmt.ModuleURL, Version: shfmt.Version},
+			{Name: "gofumpt", PathURL: gofumpt.PathURL, Module: gofumpt.ModuleURL, Version: "v0.1.0"},
+			{Name: "stringer", PathURL: stringer.PathURL, Module: stringer.ModuleURL, Version: "v0.2.0"},
+			{Name: "gopls", PathURL: "golang.org/x/tools/gopls", Module: "golang.org/x/tools/gopls", Version: "v0.14.2"},
+		},
+	}
+
+	actions := func(steps []SyncStep) map[string]SyncAction {
+		actions := make(map[string]SyncAction, len(steps))
+		for _, step := range steps {
+			actions[step.Binary.Name] = step.Action
+		}
+		return actions
+	}
+
+	steps := planSync(results, syncedManifest, goEnvironment{binDirs: []string{gobinariestest.GOBIN}}, mockFilesystemUtils{}, false)
+	assert.Equal(t, map[string]SyncAction{
+		"shfmt":      SyncActionNone,
+		"gofumpt":    SyncActionDowngrade,
+		"stringer":   SyncActionHold,
+		"gopls":      SyncActionInstall,
+		"goimports":  SyncActionKeep,
+		"local-tool": SyncActionKeep,
+		"script":     SyncActionKeep,
+	}, actions(steps))
+
+	steps = planSync(results, syncedManifest, goEnvironment{binDirs: []string{gobinariestest.GOBIN}}, mockFilesystemUtils{}, true)
+	assert.Equal(t, SyncActionRemove, actions(steps)["goimports"])
+	assert.Equal(t, SyncActionKeep, actions(steps)["script"], "files that are not Go binaries must not be pruned")
+	assert.Equal(t, SyncActionKeep, actions(steps)["local-tool"], "binaries built from source must not be pruned")
+}
+
+func TestPlanSyncComparesBinariesInGOBIN(t *testing.T) {
+	otherDir := filepath.Join("/home", "test", ".local", "bin")
+	shfmt := gobinariestest.GetShfmtMockBinary().Binary
+	shfmt.BuildTags = []string{"netgo"}
+	otherShfmt := gobinariestest.GetShfmtMockBinary().Binary
+	otherShfmt.Path = filepath.Join(otherDir, otherShfmt.Name)
+	otherShfmt.Version = "v3.0.0"
+	gofumpt := gobinariestest.GetGofumptMockBinary().Binary
+
+	results := []gobinaries.IntrospectionResult{
+		{Binary: shfmt},
+		{Binary: gofumpt},
+		// NOTE: the binary with the same name in another directory comes last.
+		{Binary: otherShfmt},
+	}
+	syncedManifest := manifest.Manifest{
+		Binaries: []manifest.Binary{
+			{Name: "shfmt", PathURL: shfmt.PathURL, Module: shfmt.ModuleURL, Version: shfmt.Version, BuildTags: []string{"netgo"}},
+			{Name: "gofumpt", PathURL: gofumpt.PathURL, Module: gofumpt.ModuleURL, Version: gofumpt.Version, BuildTags: []string{"netgo"}},
+		},
+	}
+
+	for _, prune := range []bool{false, true} {
+		steps := planSync(results, syncedManifest, goEnvironment{binDirs: []string{gobinariestest.GOBIN, otherDir}}, mockFilesystemUtils{}, prune)
+
+		actions := make(map[string]SyncAction, len(steps))
+		for _, step := range steps {
+			actions[step.Binary.Path] = step.Action
+		}
+		// NOTE: binaries outside of GOBIN are never pruned.
+		assert.Equal(t, map[string]SyncAction{
+			shfmt.Path:      SyncActionNone,
+			gofumpt.Path:    SyncActionReinstall,
+			otherShfmt.Path: SyncActionKeep,
+		}, actions, "prune: %v", prune)
+	}
+}
+
+func TestSyncManifest(t *testing.T) {
+	shfmtMockBinary := gobinariestest.GetShfmtMockBinary()
+	gofumptMockBinary := gobinariestest.GetGofumptMockBinary()
+	goimportsMockBinary := gobinariestest.GetGoimportsMockBinary()
+
+	syncedManifest := manifest.Manifest{
+		Binaries: []manifest.Binary{
+			{Name: "shfmt", PathURL: shfmtMockBinary.Binary.PathURL, Module: shfmtMockBinary.Binary.ModuleURL, Version: "v3.5.0"},
+			{Name: "gofumpt", PathURL: gofumptMockBinary.Binary.PathURL, Module: gofumptMockBinary.Binary.ModuleURL, Version: "v0.1.0"},
+			{Name: "gopls", PathURL: "golang.org/x/tools/gopls", Module: "golang.org/x/tools/gopls", Version: "v0.14.2"},
+		},
+	}
+
+	logger := zap.NewNop()
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		Entries: []string{
+			shfmtMockBinary.Binary.Name,
+			gofumptMockBinary.Binary.Name,
+			goimportsMockBinary.Binary.Name,
+		},
+	}
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinMockResponse(),
+			gobinariestest.GetModuleInfoMockResponse(shfmtMockBinary),
+			gobinariestest.GetModuleInfoMockResponse(gofumptMockBinary),
+			gobinariestest.GetModuleInfoMockResponse(goimportsMockBinary),
+			{Args: []string{"install", "mvdan.cc/sh/v3/cmd/shfmt@v3.5.0"}},
+			stagedBuildInfoMockResponse(withLatestVersion(shfmtMockBinary.Binary, "v3.5.0")),
+			{Args: []string{"install", "mvdan.cc/gofumpt@v0.1.0"}},
+			stagedBuildInfoMockResponse(withLatestVersion(gofumptMockBinary.Binary, "v0.1.0")),
+			{Args: []string{"install", "golang.org/x/tools/gopls@v0.14.2"}},
+			stagedBuildInfoMockResponse(gobinaries.GoBinary{
+				PathURL:       "golang.org/x/tools/gopls",
+				ModuleURL:     "golang.org/x/tools/gopls",
+				Path:          filepath.Join(gobinariestest.GOBIN, "gopls"),
+				LatestVersion: "v0.14.2",
+			}),
+		},
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := recordingFilesystemUtils{}
+	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
+
+	err := SyncManifest(context.Background(), logger, syncedManifest, SyncOptions{Prune: true}, &reporter, &cmdRunner, &lister, &buildInfoReader, &fsutils)
+
+	require.Nil(t, err)
+	assert.Equal(t, []string{goimportsMockBinary.Binary.Path}, fsutils.removedPaths)
+	assert.Equal(t, strings.TrimSpace(`
+Binary         Current version      Plan
+shfmt          v3.4.2               upgrade to v3.5.0
+gofumpt        v0.3.0               downgrade to v0.1.0
+gopls          -                    install v0.14.2
+goimports      v0.1.12              remove
+
+Upgrading shfmt to v3.5.0 ... ✅
+
+Downgrading gofumpt to v0.1.0 ... ✅
+
+Installing gopls v0.14.2 ... ✅
+
+Removing goimports ... ✅
+`), strings.TrimSpace(output.String()))
+}
+
+func TestSyncManifestDryRun(t *testing.T) {
+	gofumptMockBinary := gobinariestest.GetGofumptMockBinary()
+
+	syncedManifest := manifest.Manifest{
+		Binaries: []manifest.Binary{
+			{Name: "gofumpt", PathURL: gofumptMockBinary.Binary.PathURL, Module: gofumptMockBinary.Binary.ModuleURL, Version: "v0.4.0"},
+		},
+	}
+
+	logger := zap.NewNop()
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		Entries: []string{gofumptMockBinary.Binary.Name},
+	}
+	// NOTE: there are no mock responses for `go install`. A dry run must not
+	// install anything.
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinMockResponse(),
+			gobinariestest.GetModuleInfoMockResponse(gofumptMockBinary),
+		},
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := recordingFilesystemUtils{}
+	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
+
+	err := SyncManifest(context.Background(), logger, syncedManifest, SyncOptions{DryRun: true, Prune: true}, &reporter, &cmdRunner, &lister, &buildInfoReader, &fsutils)
+
+	require.Nil(t, err)
+	assert.Empty(t, fsutils.removedPaths)
+	assert.Equal(t, strings.TrimSpace(`
+Binary       Current version      Plan
+gofumpt      v0.3.0               upgrade to v0.4.0
+`), strings.TrimSpace(output.String()))
+}
@@ -0,0 +1,502 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Gelio/go-global-update/internal/colors"
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+	"github.com/fatih/color"
+)
+
+// TextReporter prints human-readable output: a table with the summary of
+// binaries followed by the progress of updating them.
+type TextReporter struct {
+	out           io.Writer
+	colorsFactory *colors.DecoratorFactory
+	verbose       bool
+
+	// printedTable determines whether the table with the summary of binaries
+	// was printed.
+	printedTable bool
+	// printedBlocks is the number of printed blocks describing updates of
+	// binaries. Blocks are separated by empty lines.
+	printedBlocks int
+	// printProblems determines whether common update problems should be
+	// printed for the current block.
+	printProblems bool
+}
+
+func NewTextReporter(out io.Writer, colorsFactory *colors.DecoratorFactory, verbose bool) TextReporter {
+	return TextReporter{
+		out:           out,
+		colorsFactory: colorsFactory,
+		verbose:       verbose,
+	}
+}
+
+func (r *TextReporter) Report(event Event) {
+	switch event := event.(type) {
+	case IntrospectedEvent:
+		r.printBinariesSummary(event.Results)
+
+	case SkippedFromSourceEvent:
+		r.startBlock()
+		r.printSkippedFromSource(event.Binary)
+		r.printProblems = true
+
+	case SkippedSymlinkEvent:
+		r.startBlock()
+		r.printSkippedSymlink(event.Binary)
+
+	case InstallStartedEvent:
+		r.startBlock()
+		r.printInstallStarted(event.Binary, event.BuildTags)
+
+	case InstallFinishedEvent:
+		r.printInstallFinished(event)
+
+	case SyncPlannedEvent:
+		r.printSyncPlan(event.Steps)
+
+	case RemovedEvent:
+		r.startBlock()
+		r.printRemoved(event)
+
+	case PathAuditedEvent:
+		r.printPathAudit(event.Findings)
+
+	case SmokeCheckFailedEvent:
+		r.printSmokeCheckFailed(event)
+
+	case ProblemDetectedEvent:
+		if r.printProblems {
+			fmt.Fprintf(r.out, "%s\n", event.Problem.String(r.colorsFactory))
+		}
+
+	case SummaryEvent:
+		if event.Interrupted {
+			r.printInterrupted(event)
+		} else if failed := event.Failed + event.Mismatched; failed > 0 {
+			r.printFailed(failed)
+		}
+		if r.printedBlocks > 0 {
+			fmt.Fprintln(r.out)
+		}
+	}
+}
+
+// printInterrupted prints a partial summary when the run was stopped early.
+func (r *TextReporter) printInterrupted(event SummaryEvent) {
+	r.startBlock()
+
+	var counts []string
+	for _, count := range []struct {
+		n     int
+		label string
+	}{
+		{event.Installed, "installed"},
+		{event.Upgraded, "upgraded"},
+		{event.Downgraded, "downgraded"},
+		{event.Reinstalled, "reinstalled"},
+		{event.Removed, "removed"},
+		{event.Failed, "failed"},
+		{event.Mismatched, "mismatched"},
+		{event.Canceled, "not started"},
+	} {
+		if count.n > 0 {
+			counts = append(counts, fmt.Sprintf("%d %s", count.n, count.label))
+		}
+	}
+	if len(counts) == 0 {
+		counts = append(counts, "nothing was changed")
+	}
+
+	fmt.Fprintf(r.out, "%s: %s\n", r.colorsFactory.NewDecorator(color.FgYellow)("Interrupted"), strings.Join(counts, ", "))
+}
+
+// printFailed prints the number of binaries that could not be updated.
+func (r *TextReporter) printFailed(failed int) {
+	r.startBlock()
+	fmt.Fprintf(r.out, "Could not install %s package(s)\n", r.colorsFactory.NewDecorator(color.FgRed, color.Bold)(failed))
+}
+
+func (r *TextReporter) startBlock() {
+	if r.printedTable || r.printedBlocks > 0 {
+		fmt.Fprintln(r.out)
+	}
+	r.printedBlocks++
+	r.printProblems = false
+}
+
+func (r *TextReporter) printBinariesSummary(introspectionResults []gobinaries.IntrospectionResult) {
+	r.printedTable = true
+	tabWriter := tabwriter.NewWriter(r.out, 0, 0, 6, ' ', tabwriter.StripEscape)
+	fmt.Fprintln(tabWriter, "Binary\tCurrent version\tStatus")
+	defer tabWriter.Flush()
+
+	for _, result := range introspectionResults {
+		if result.Error != nil {
+			if kind := gobinaries.ClassifyIntrospectionError(result.Error); kind.NotGoBinary() {
+				fmt.Fprintf(tabWriter, "%s\t%s\t%s\n", result.Binary.Name, "-", r.notGoBinaryInfo(kind))
+			} else {
+				fmt.Fprintln(r.out, result.Error)
+			}
+			continue
+		}
+
+		binary := result.Binary
+		latestVersionInfo := r.latestVersionInfo(binary)
+
+		name := binary.Name
+		if r.verbose {
+			name = binary.PathURL
+		}
+		name = withSymlinkTarget(withPlatform(name, binary), binary.Path, binary.SymlinkTarget)
+
+		// NOTE: only the last column can safely use ANSI color codes. Otherwise,
+		// column widths can be mismatched due to color codes used only in some
+		// rows.
+		// @see https://stackoverflow.com/questions/35398497/how-do-i-get-colors-to-work-with-golang-tabwriter
+		fmt.Fprintf(tabWriter, "%s\t%s\t%s\n", name, binary.Version, latestVersionInfo)
+	}
+}
+
+var notGoBinaryDescriptions = map[gobinaries.IntrospectionErrorKind]string{
+	gobinaries.IntrospectionErrorNotGoBinary:      "not a Go binary",
+	gobinaries.IntrospectionErrorDirectory:        "directory",
+	gobinaries.IntrospectionErrorNoBuildInfo:      "no build info (stripped or GOPATH mode)",
+	gobinaries.IntrospectionErrorPermissionDenied: "permission denied",
+}
+
+func (r *TextReporter) notGoBinaryInfo(kind gobinaries.IntrospectionErrorKind) string {
+	return r.colorsFactory.NewDecorator(color.Faint)(notGoBinaryDescriptions[kind])
+}
+
+var upgradeKinds = map[gobinaries.UpgradeState]string{
+	gobinaries.UpgradeStatePatchBehind: "patch",
+	gobinaries.UpgradeStateMinorBehind: "minor",
+	gobinaries.UpgradeStateMajorBehind: "major",
+}
+
+func (r *TextReporter) latestVersionInfo(binary gobinaries.GoBinary) string {
+	if binary.LatestVersion == "" {
+		return r.colorsFactory.NewDecorator(color.FgYellow)("cannot upgrade")
+	}
+
+	faintFormatter := r.colorsFactory.NewDecorator(color.Faint)
+
+	var latestVersionInfo string
+	// notes are printed in parentheses after latestVersionInfo.
+	var notes []string
+	switch {
+	case binary.UpgradePossible():
+		latestVersionInfo = fmt.Sprintf("can upgrade to %s", r.colorsFactory.NewDecorator(color.FgGreen)(binary.LatestVersion))
+		if binary.PathChanged() {
+			latestVersionInfo += fmt.Sprintf(" from %s", binary.InstallPathURL)
+		} else if upgradeKind := upgradeKinds[binary.UpgradeState()]; upgradeKind != "" {
+			notes = append(notes, upgradeKind)
+		}
+	case binary.DowngradePossible() && binary.VersionRequested:
+		latestVersionInfo = fmt.Sprintf("can downgrade to %s", r.colorsFactory.NewDecorator(color.FgYellow)(binary.LatestVersion))
+	case binary.DowngradePossible():
+		latestVersionInfo = fmt.Sprintf("%s %s", r.colorsFactory.NewDecorator(color.FgYellow)("newer than latest"),
+			faintFormatter(fmt.Sprintf("(latest: %s)", binary.LatestVersion)))
+	default:
+		latestVersionInfo = "up-to-date"
+	}
+
+	if binary.VersionRequested {
+		notes = append(notes, fmt.Sprintf("requested %s", binary.VersionQuery))
+	}
+	if binary.NewMajorVersion != "" {
+		notes = append(notes, fmt.Sprintf("new major version available: %s", binary.NewMajorVersion))
+	}
+	if len(notes) > 0 {
+		latestVersionInfo += faintFormatter(fmt.Sprintf(" (%s)", strings.Join(notes, ", ")))
+	}
+
+	if binary.Held {
+		latestVersionInfo = fmt.Sprintf("%s %s", r.colorsFactory.NewDecorator(color.FgYellow)("held"),
+			faintFormatter(fmt.Sprintf("(%s)", latestVersionInfo)))
+	}
+
+	return latestVersionInfo
+}
+
+func (r *TextReporter) printSyncPlan(steps []SyncStep) {
+	r.printedTable = true
+	tabWriter := tabwriter.NewWriter(r.out, 0, 0, 6, ' ', tabwriter.StripEscape)
+	fmt.Fprintln(tabWriter, "Binary\tCurrent version\tPlan")
+	defer tabWriter.Flush()
+
+	for _, step := range steps {
+		if step.Error != nil {
+			kind := gobinaries.ClassifyIntrospectionError(step.Error)
+			if step.Action == SyncActionKeep && kind.NotGoBinary() {
+				fmt.Fprintf(tabWriter, "%s\t%s\t%s\n", step.Binary.Name, "-", r.notGoBinaryInfo(kind))
+				continue
+			}
+
+			fmt.Fprintln(r.out, step.Error)
+			if step.Action == SyncActionKeep {
+				continue
+			}
+		}
+
+		binary := step.Binary
+		name := binary.Name
+		if r.verbose {
+			name = binary.PathURL
+		}
+		name = withSymlinkTarget(name, binary.Path, binary.SymlinkTarget)
+
+		currentVersion := binary.Version
+		if currentVersion == "" {
+			currentVersion = "-"
+		}
+
+		// NOTE: only the last column can use ANSI color codes. See
+		// printBinariesSummary.
+		fmt.Fprintf(tabWriter, "%s\t%s\t%s\n", name, currentVersion, r.syncActionInfo(step))
+	}
+}
+
+func (r *TextReporter) syncActionInfo(step SyncStep) string {
+	binary := step.Binary
+	faintFormatter := r.colorsFactory.NewDecorator(color.Faint)
+	versionFormatter := r.colorsFactory.NewDecorator(color.FgGreen)
+
+	switch step.Action {
+	case SyncActionHold:
+		return fmt.Sprintf("%s %s", r.colorsFactory.NewDecorator(color.FgYellow)("held"),
+			faintFormatter(fmt.Sprintf("(manifest version: %s)", binary.LatestVersion)))
+	case SyncActionInstall:
+		return fmt.Sprintf("install %s", versionFormatter(binary.LatestVersion))
+	case SyncActionUpgrade:
+		return fmt.Sprintf("upgrade to %s", versionFormatter(binary.LatestVersion))
+	case SyncActionDowngrade:
+		return fmt.Sprintf("downgrade to %s", r.colorsFactory.NewDecorator(color.FgYellow)(binary.LatestVersion))
+	case SyncActionReinstall:
+		info := fmt.Sprintf("reinstall %s", versionFormatter(binary.LatestVersion))
+		if binary.PathChanged() {
+			info += fmt.Sprintf(" from %s", binary.InstallPathURL)
+		}
+		return info
+	case SyncActionRemove:
+		return r.colorsFactory.NewDecorator(color.FgRed)("remove")
+	case SyncActionKeep:
+		return faintFormatter("not in manifest")
+	default:
+		return "up-to-date"
+	}
+}
+
+func (r *TextReporter) printPathAudit(findings []PathAuditFinding) {
+	if len(findings) == 0 {
+		fmt.Fprintln(r.out, "No problems with go binaries in PATH")
+		return
+	}
+
+	r.printedTable = true
+	tabWriter := tabwriter.NewWriter(r.out, 0, 0, 6, ' ', tabwriter.StripEscape)
+	fmt.Fprintln(tabWriter, "Binary\tPath\tProblem")
+	defer tabWriter.Flush()
+
+	for _, finding := range findings {
+		// NOTE: only the last column can use ANSI color codes. See
+		// printBinariesSummary.
+		fmt.Fprintf(tabWriter, "%s\t%s\t%s\n", finding.Binary.Name, finding.Binary.Path, r.pathAuditInfo(finding))
+	}
+}
+
+func (r *TextReporter) pathAuditInfo(finding PathAuditFinding) string {
+	faintFormatter := r.colorsFactory.NewDecorator(color.Faint)
+	warningFormatter := r.colorsFactory.NewDecorator(color.FgYellow)
+
+	switch finding.Kind {
+	case PathAuditShadowed:
+		info := fmt.Sprintf("%s by %s", warningFormatter("shadowed"), finding.ShadowedByPath)
+		switch finding.ShadowedByVersion {
+		case "":
+		case finding.Binary.Version:
+			info += faintFormatter(fmt.Sprintf(" (same version %s)", finding.ShadowedByVersion))
+		default:
+			info += faintFormatter(fmt.Sprintf(" (%s instead of %s)", finding.ShadowedByVersion, finding.Binary.Version))
+		}
+		return info
+	case PathAuditOutdated:
+		return fmt.Sprintf("%s %s", warningFormatter("outdated"),
+			faintFormatter(fmt.Sprintf("(%s, latest: %s)", finding.Binary.Version, finding.Binary.LatestVersion)))
+	default:
+		return fmt.Sprintf("%s via PATH", warningFormatter("not reachable"))
+	}
+}
+
+func (r *TextReporter) printRemoved(event RemovedEvent) {
+	binaryNameFormatter := r.colorsFactory.NewDecorator(color.FgCyan)
+
+	fmt.Fprintf(r.out, "Removing %s ... ", binaryNameFormatter(event.Binary.Name))
+	if event.Error != nil {
+		fmt.Fprintln(r.out, "❌")
+		fmt.Fprintf(r.out, "    Could not remove binary: %v\n", event.Error)
+	} else {
+		fmt.Fprintln(r.out, "✅")
+	}
+}
+
+func (r *TextReporter) printSkippedFromSource(binary gobinaries.GoBinary) {
+	binaryNameFormatter := r.colorsFactory.NewDecorator(color.FgCyan)
+	faintFormatter := r.colorsFactory.NewDecorator(color.Faint)
+
+	verb := "reinstalling"
+	if binary.UpgradePossible() {
+		verb = "upgrading"
+	}
+	fmt.Fprintf(r.out, "Skipping %s %s\n    ", verb, binaryNameFormatter(withPlatform(binary.Name, binary)))
+	if binary.BuiltWithGoBuild() {
+		fmt.Fprintf(r.out, "The binary was built from source (probably using \"%s\") and the binary path is unknown.\n",
+			faintFormatter("go build"))
+	} else {
+		fmt.Fprintf(r.out, "The binary was installed from source (probably using \"%s\" in the cloned repository).\n",
+			faintFormatter("go install"))
+	}
+	pathURL := binary.PathURL
+	if binary.BuiltWithGoBuild() {
+		// NOTE: binaries built with `go build` have `command-line-arguments`
+		// as their `path` which would not make sense in help message.
+		pathURL = "repositoryPath"
+	}
+
+	fmt.Fprintf(r.out, "    Install the binary using \"%s\" instead.\n",
+		faintFormatter(fmt.Sprintf("go install %s@latest", pathURL)))
+}
+
+func (r *TextReporter) printSkippedSymlink(binary gobinaries.GoBinary) {
+	binaryNameFormatter := r.colorsFactory.NewDecorator(color.FgCyan)
+	faintFormatter := r.colorsFactory.NewDecorator(color.Faint)
+
+	verb := "reinstalling"
+	if binary.UpgradePossible() {
+		verb = "upgrading"
+	}
+	fmt.Fprintf(r.out, "Skipping %s %s\n", verb, binaryNameFormatter(binary.Name))
+	fmt.Fprintf(r.out, "    The binary is a symlink to %s.\n", binary.SymlinkTarget)
+	fmt.Fprintf(r.out, "    Use \"%s\" or \"%s\" to update it.\n",
+		faintFormatter("--symlinks=target"), faintFormatter("--symlinks=retarget"))
+}
+
+func (r *TextReporter) printInstallStarted(binary gobinaries.GoBinary, buildTags []string) {
+	binaryNameFormatter := func(name string) string {
+		return r.colorsFactory.NewDecorator(color.FgCyan)(withPlatform(name, binary))
+	}
+	faintFormatter := r.colorsFactory.NewDecorator(color.Faint)
+	latestVersionFormatter := r.colorsFactory.NewDecorator(color.FgGreen)
+
+	var buildTagsInfo string
+	if len(buildTags) > 0 {
+		buildTagsInfo = fmt.Sprintf(" (build tags: %s)", faintFormatter(strings.Join(buildTags, ",")))
+	}
+
+	switch {
+	case binary.Version == "":
+		version := binary.LatestVersion
+		if version == "" {
+			version = "latest"
+		}
+		fmt.Fprintf(r.out, "Installing %s %s%s ... ", binaryNameFormatter(binary.Name),
+			latestVersionFormatter(version), buildTagsInfo)
+	case binary.DowngradePossible():
+		fmt.Fprintf(r.out, "Downgrading %s to %s%s ... ", binaryNameFormatter(binary.Name),
+			r.colorsFactory.NewDecorator(color.FgYellow)(binary.LatestVersion), buildTagsInfo)
+	case binary.UpgradePossible():
+		fmt.Fprintf(r.out, "Upgrading %s to %s%s ... ", binaryNameFormatter(binary.Name),
+			latestVersionFormatter(binary.LatestVersion), buildTagsInfo)
+	default:
+		fmt.Fprintf(r.out, "Force-reinstalling %s %s%s ... ", binaryNameFormatter(binary.Name),
+			latestVersionFormatter(binary.LatestVersion), buildTagsInfo)
+	}
+}
+
+func (r *TextReporter) printInstallFinished(event InstallFinishedEvent) {
+	var verificationErr *VerificationError
+	if errors.As(event.Error, &verificationErr) {
+		fmt.Fprintln(r.out, "❌")
+		fmt.Fprintln(r.out, "    The installed binary does not match the plan:")
+		for _, mismatch := range verificationErr.Mismatches {
+			fmt.Fprintf(r.out, "      - %s\n", mismatch)
+		}
+	} else if event.Error != nil {
+		fmt.Fprintln(r.out, "❌")
+		if len(event.Output) > 0 {
+			fmt.Fprintln(r.out, "    Could not install package")
+		} else {
+			fmt.Fprintf(r.out, "    Could not install package: %v\n", event.Error)
+		}
+	} else {
+		fmt.Fprintln(r.out, "✅")
+	}
+
+	if event.SymlinkTarget != "" {
+		fmt.Fprintf(r.out, "    Linked %s\n", withSymlinkTarget(event.Binary.Name, event.Binary.Path, event.SymlinkTarget))
+	}
+
+	warningFormatter := r.colorsFactory.NewDecorator(color.FgYellow)
+	faintFormatter := r.colorsFactory.NewDecorator(color.Faint)
+	for _, setting := range event.DroppedSettings {
+		fmt.Fprintf(r.out, "    %s: could not replicate the build setting %s\n",
+			warningFormatter("Warning"), faintFormatter(setting.Key+"="+setting.Value))
+	}
+
+	if len(event.Output) > 0 && (r.verbose || event.Error != nil) {
+		fmt.Fprintln(r.out, event.Output)
+		r.printProblems = true
+	}
+}
+
+func (r *TextReporter) printSmokeCheckFailed(event SmokeCheckFailedEvent) {
+	faintFormatter := r.colorsFactory.NewDecorator(color.Faint)
+
+	command := strings.Join(append([]string{event.Binary.Name}, event.Args...), " ")
+	fmt.Fprintf(r.out, "    Smoke check \"%s\" failed: %v\n", faintFormatter(command), event.Error)
+	if len(event.Output) > 0 {
+		fmt.Fprintln(r.out, strings.TrimRight(event.Output, "\n"))
+	}
+
+	switch {
+	case event.RolledBack:
+		fmt.Fprintf(r.out, "    Rolled back to %s\n", event.Binary.Version)
+	case event.RollbackError != nil:
+		fmt.Fprintf(r.out, "    Could not roll back: %v\n", event.RollbackError)
+	default:
+		fmt.Fprintln(r.out, "    There is no backup to roll back to")
+	}
+}
+
+// withPlatform appends the platform of cross-compiled binaries to the name, so
+// they can be told apart from the binaries for the host.
+func withPlatform(name string, binary gobinaries.GoBinary) string {
+	if !binary.CrossCompiled() {
+		return name
+	}
+
+	return fmt.Sprintf("%s (%s/%s)", name, binary.Setting("GOOS"), binary.Setting("GOARCH"))
+}
+
+// withSymlinkTarget appends the target of the symlink at path to the name.
+// Targets in the same directory as the symlink are shortened to the file name.
+func withSymlinkTarget(name, path, target string) string {
+	if target == "" {
+		return name
+	}
+
+	if filepath.Dir(target) == filepath.Dir(path) {
+		target = filepath.Base(target)
+	}
+
+	return fmt.Sprintf("%s -> %s", name, target)
+}
@@ -1,241 +1,690 @@
 package updater
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"io"
+	"os"
 	"path/filepath"
+	"runtime"
+	"runtime/debug"
 	"strings"
-	"text/tabwriter"
 
-	"github.com/Gelio/go-global-update/internal/colors"
+	"github.com/Gelio/go-global-update/internal/backup"
+	"github.com/Gelio/go-global-update/internal/config"
 	"github.com/Gelio/go-global-update/internal/gobinaries"
 	"github.com/Gelio/go-global-update/internal/gocli"
-	"github.com/fatih/color"
 	"go.uber.org/zap"
 )
 
 type Options struct {
-	Verbose bool
-	DryRun  bool
+	DryRun bool
 	// List of binary names to update.
 	// If empty, will update all binaries in GOBIN
+	//
+	// A name can have a version query suffix (for example `gopls@v0.14.2`,
+	// `gopls@latest`, `gopls@master`, or `gopls@v0`). The binary is then
+	// installed at the resolved version, even if that is a downgrade.
 	BinariesToUpdate []string
 	// Whether to force reinstalling/updating all binaries.
 	ForceReinstall bool
+	// Whether to downgrade binaries that are newer than the latest version.
+	//
+	// Otherwise, such binaries are left alone, or reinstalled at their current
+	// version when ForceReinstall is set.
+	AllowDowngrade bool
+	// Whether to install binaries from the newest major version of their
+	// modules (for example from `mvdan.cc/sh/v4` instead of `mvdan.cc/sh/v3`).
+	AllowMajor bool
+	// Whether to install `@latest` (or the version query from the config
+	// file) again instead of the exact version resolved when checking for
+	// updates. The installed version may then differ from the reported one.
+	InstallLatest bool
+	// Config contains per-binary settings from the config file.
+	Config config.Config
+	// Dirs are additional directories with go binaries, scanned after GOBIN
+	// and GOPATH.
+	Dirs []string
+	// Jobs is the maximum number of binaries installed concurrently. If it is
+	// not positive, GOMAXPROCS is used.
+	Jobs int
+	// Backups stores binaries from before they were updated. If nil, no
+	// backups are made.
+	Backups *backup.Store
+	// Whether to hide entries that are not go binaries, for example shell
+	// scripts or directories.
+	IgnoreNonGo bool
+	// Symlinks determines how binaries that are symlinks are updated. The
+	// setting from the config file takes precedence.
+	Symlinks SymlinkMode
 }
 
 // UpdateBinaries updates binaries in GOBIN
 //
 // If binariesToUpdate is empty, the command will attempt to update all
 // found binaries in GOBIN.
+//
+// The progress and the results are reported as events to the reporter.
+//
+// Once ctx is done, no new binaries are installed. Installs that are already
+// running are interrupted, and the summary of the finished installs is still
+// reported.
 func UpdateBinaries(
+	ctx context.Context,
 	logger *zap.Logger,
 	options Options,
-	out io.Writer,
-	colorsFactory *colors.DecoratorFactory,
+	reporter Reporter,
 	cmdRunner gocli.GoCmdRunner,
 	lister gobinaries.DirectoryLister,
+	buildInfoReader gobinaries.BuildInfoReader,
 	fs FilesystemUtils,
+	smokeChecker SmokeChecker,
 ) error {
-	goCLI := gocli.New(cmdRunner)
-	gobin, err := getExecutableBinariesPath(&goCLI)
+	binaryNames, versionQueries, err := parseBinaryArgs(options.BinariesToUpdate)
 	if err != nil {
-		return fmt.Errorf("could not determine GOBIN path: %w", err)
+		return err
 	}
 
-	logger.Debug("found GOBIN path", zap.String("GOBIN", gobin))
-
-	if err := fs.Chdir(gobin); err != nil {
-		return fmt.Errorf("could not change directory to GOBIN (%s): %w", gobin, err)
+	env, err := findGoEnvironment(ctx, logger, cmdRunner, options.Dirs, fs)
+	if err != nil {
+		return err
 	}
 
-	introspecter := gobinaries.NewIntrospecter(cmdRunner, gobin, logger)
-	binaryNames, err := resolveBinaryNames(options.BinariesToUpdate, lister, gobin)
+	hermeticCmdRunner := gocli.NewHermeticCmdRunner(cmdRunner, env.gobin())
+	goCLI := gocli.New(&hermeticCmdRunner)
+	introspecter := gobinaries.NewIntrospecter(&hermeticCmdRunner, buildInfoReader, env.GOEXE, logger)
+	introspecter.SetOverrides(withRequestedVersions(getOverrides(options.Config), versionQueries))
+	// NOTE: new major versions are shown in the status of the binaries, so
+	// they are only looked up here and not in the other commands.
+	introspecter.SetFindNewMajorVersions(true)
+	binaryPaths, crossCompiledPaths, err := resolveBinaryPaths(binaryNames, lister, env)
 	if err != nil {
 		return err
 	}
 
-	introspectionResults := gobinaries.IntrospectBinaries(&introspecter, binaryNames)
-	printBinariesSummary(introspectionResults, out, colorsFactory, options.Verbose)
+	introspectionResults := gobinaries.IntrospectBinaries(ctx, &introspecter, append(binaryPaths, crossCompiledPaths...))
+	resolveSymlinks(introspectionResults, fs)
+	if options.IgnoreNonGo {
+		introspectionResults = withoutNonGoEntries(introspectionResults)
+	}
+	if options.AllowMajor {
+		for i := range introspectionResults {
+			if binary := &introspectionResults[i].Binary; !binary.Held && !binary.VersionRequested {
+				binary.UseNewMajorVersion()
+			}
+		}
+	}
+	reporter.Report(IntrospectedEvent{Results: introspectionResults})
 
+	summary := SummaryEvent{DryRun: options.DryRun}
 	if !options.DryRun {
-		return updateBinaries(introspectionResults, &goCLI, out, colorsFactory, options)
+		installer := newInstaller(&goCLI, buildInfoReader, env, fs)
+		updateBinaries(ctx, introspectionResults, &installer, smokeChecker, reporter, options, env, fs, &summary)
+	}
+	summary.Interrupted = ctx.Err() != nil
+	reporter.Report(summary)
+
+	if err := interruptedError(ctx); err != nil {
+		return err
+	}
+
+	return failedError(summary)
+}
+
+// interruptedError returns an error explaining why the run was stopped early,
+// or nil if ctx is not done.
+func interruptedError(ctx context.Context) error {
+	switch {
+	case ctx.Err() == nil:
+		return nil
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("the run timed out: %w", ctx.Err())
+	default:
+		return fmt.Errorf("the run was interrupted: %w", ctx.Err())
+	}
+}
+
+// FailedError means some binaries could not be installed. The failures are
+// already reported by the reporter, so the error does not need to be printed
+// again.
+type FailedError struct {
+	// Failed is the number of binaries that could not be installed or do not
+	// match the plan.
+	Failed int
+}
+
+func (e *FailedError) Error() string {
+	return fmt.Sprintf("could not install %d package(s)", e.Failed)
+}
+
+// failedError returns a FailedError if some binaries from the summary could
+// not be installed, or nil otherwise.
+func failedError(summary SummaryEvent) error {
+	if failed := summary.Failed + summary.Mismatched; failed > 0 {
+		return &FailedError{Failed: failed}
 	}
 
 	return nil
 }
 
-func resolveBinaryNames(binariesToUpdate []string, lister gobinaries.DirectoryLister, gobin string) ([]string, error) {
-	if len(binariesToUpdate) > 0 {
-		return binariesToUpdate, nil
+// goEnvironment describes where go binaries are installed.
+type goEnvironment struct {
+	gocli.GoEnv
+	// binDirs are the directories with go binaries. New binaries are installed
+	// into the first one.
+	binDirs []string
+}
+
+// gobin returns the directory into which new binaries are installed.
+func (env *goEnvironment) gobin() string {
+	return env.binDirs[0]
+}
+
+// isGOPATHBinDir determines whether dir is the bin directory of a GOPATH
+// entry.
+func (env *goEnvironment) isGOPATHBinDir(dir string) bool {
+	for _, gopath := range filepath.SplitList(env.GOPATH) {
+		if gopath != "" && filepath.Join(gopath, "bin") == dir {
+			return true
+		}
 	}
 
-	binaryNames, err := lister.ListDirectoryEntries(gobin)
+	return false
+}
+
+// newBinaryPath returns the path of a new binary installed into GOBIN.
+func (env *goEnvironment) newBinaryPath(binaryName string) string {
+	return filepath.Join(env.gobin(), binaryName+env.GOEXE)
+}
+
+// findGoEnvironment reads the go environment and makes sure GOBIN exists.
+//
+// extraDirs are scanned for binaries after GOBIN and GOPATH.
+func findGoEnvironment(
+	ctx context.Context,
+	logger *zap.Logger,
+	cmdRunner gocli.GoCmdRunner,
+	extraDirs []string,
+	fs FilesystemUtils,
+) (goEnvironment, error) {
+	hermeticCmdRunner := gocli.NewHermeticCmdRunner(cmdRunner, "")
+	goCLI := gocli.New(&hermeticCmdRunner)
+	goEnv, err := goCLI.GetEnv(ctx)
+	if err := interruptedError(ctx); err != nil {
+		return goEnvironment{}, err
+	}
 	if err != nil {
-		err = fmt.Errorf("could not list GOBIN (%s) entries: %w", gobin, err)
+		return goEnvironment{}, fmt.Errorf("could not read the go environment: %w", err)
 	}
 
-	return binaryNames, err
-}
+	env := goEnvironment{GoEnv: goEnv, binDirs: goEnv.BinDirs()}
+	if len(env.binDirs) == 0 {
+		return goEnvironment{}, errors.New("neither GOBIN nor GOPATH is set in 'go env'")
+	}
+	for _, dir := range extraDirs {
+		// NOTE: the directory is used as GOBIN when installing binaries found
+		// in it, and GOBIN must be an absolute path.
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return goEnvironment{}, fmt.Errorf("could not resolve directory %s: %w", dir, err)
+		}
+		if !containsString(env.binDirs, absDir) {
+			env.binDirs = append(env.binDirs, absDir)
+		}
+	}
 
-func printBinariesSummary(
-	introspectionResults []gobinaries.IntrospectionResult,
-	out io.Writer,
-	colorsFactory *colors.DecoratorFactory,
-	verbose bool,
-) {
-	tabWriter := tabwriter.NewWriter(out, 0, 0, 6, ' ', tabwriter.StripEscape)
-	fmt.Fprintln(tabWriter, "Binary\tCurrent version\tStatus")
-	defer tabWriter.Flush()
+	logger.Debug("found go environment",
+		zap.Strings("binDirs", env.binDirs),
+		zap.String("GOVERSION", env.GOVERSION),
+		zap.String("GOOS", env.GOOS),
+		zap.String("GOARCH", env.GOARCH),
+		zap.String("GOPROXY", env.GOPROXY),
+		zap.String("GOFLAGS", env.GOFLAGS),
+		zap.String("GOTOOLCHAIN", env.GOTOOLCHAIN),
+	)
+
+	// NOTE: GOBIN may not exist yet, for example before importing a manifest
+	// on a new machine. go commands are run in GOBIN, so it has to exist.
+	if err := fs.MkdirAll(env.gobin()); err != nil {
+		return goEnvironment{}, fmt.Errorf("could not create GOBIN (%s): %w", env.gobin(), err)
+	}
+
+	return env, nil
+}
 
+// withoutNonGoEntries removes the results of entries that are known not to be
+// go binaries that can be updated.
+func withoutNonGoEntries(introspectionResults []gobinaries.IntrospectionResult) []gobinaries.IntrospectionResult {
+	var goBinaryResults []gobinaries.IntrospectionResult
 	for _, result := range introspectionResults {
-		if result.Error != nil {
-			fmt.Fprintln(out, result.Error)
-			continue
+		if result.Error == nil || !gobinaries.ClassifyIntrospectionError(result.Error).NotGoBinary() {
+			goBinaryResults = append(goBinaryResults, result)
 		}
+	}
 
-		binary := result.Binary
-		var latestVersionInfo string
-		if binary.LatestVersion != "" {
-			if binary.UpgradePossible() {
-				latestVersionInfo = fmt.Sprintf("can upgrade to %s", colorsFactory.NewDecorator(color.FgGreen)(binary.LatestVersion))
-			} else {
-				latestVersionInfo = "up-to-date"
+	return goBinaryResults
+}
+
+// resolveBinaryPaths returns the paths of the binaries to introspect.
+//
+// If binaryNames is empty, all entries of the bin directories are returned.
+// Otherwise, each binary is looked up in the bin directories, in order.
+// Binaries that are not found are expected in GOBIN.
+//
+// crossCompiledPaths are the paths of the binaries with the same names from
+// the `<goos>_<goarch>` subdirectories of GOPATH/bin. They are returned
+// separately, because their names clash with the binaries for the host.
+func resolveBinaryPaths(
+	binaryNames []string,
+	lister gobinaries.DirectoryLister,
+	env goEnvironment,
+) (binaryPaths []string, crossCompiledPaths []string, err error) {
+	dirEntries := make([][]string, len(env.binDirs))
+	for i, dir := range env.binDirs {
+		entries, err := lister.ListDirectoryEntries(dir)
+		// NOTE: only GOBIN is created. The bin directories of other GOPATH
+		// entries and the additional directories may not exist.
+		if err != nil && (i == 0 || !errors.Is(err, os.ErrNotExist)) {
+			return nil, nil, fmt.Errorf("could not list entries of %s: %w", dir, err)
+		}
+		if env.isGOPATHBinDir(dir) {
+			var platformPaths []string
+			entries, platformPaths = listPlatformDirs(lister, dir, entries)
+			crossCompiledPaths = append(crossCompiledPaths, platformPaths...)
+		}
+		dirEntries[i] = entries
+	}
+
+	if len(binaryNames) == 0 {
+		for i, dir := range env.binDirs {
+			for _, entry := range dirEntries[i] {
+				binaryPaths = append(binaryPaths, filepath.Join(dir, entry))
 			}
-		} else {
-			latestVersionInfo = colorsFactory.NewDecorator(color.FgYellow)("cannot upgrade")
 		}
 
-		name := binary.Name
-		if verbose {
-			name = binary.PathURL
+		return binaryPaths, crossCompiledPaths, nil
+	}
+
+	for _, binaryName := range binaryNames {
+		binaryPaths = append(binaryPaths, findBinaryPath(binaryName, env, dirEntries))
+	}
+
+	var namedCrossCompiledPaths []string
+	for _, crossCompiledPath := range crossCompiledPaths {
+		if containsString(binaryNames, gobinaries.BinaryName(crossCompiledPath, env.GOEXE)) {
+			namedCrossCompiledPaths = append(namedCrossCompiledPaths, crossCompiledPath)
+		}
+	}
+
+	return binaryPaths, namedCrossCompiledPaths, nil
+}
+
+// listPlatformDirs lists the binaries in the `<goos>_<goarch>` subdirectories
+// of dir. It returns the remaining entries of dir and the paths of the
+// binaries from the subdirectories.
+func listPlatformDirs(lister gobinaries.DirectoryLister, dir string, entries []string) ([]string, []string) {
+	var remainingEntries, platformPaths []string
+	for _, entry := range entries {
+		if !gobinaries.IsPlatformDir(entry) {
+			remainingEntries = append(remainingEntries, entry)
+			continue
+		}
+
+		platformDir := filepath.Join(dir, entry)
+		platformEntries, err := lister.ListDirectoryEntries(platformDir)
+		// NOTE: the entry is not a directory after all
+		if err != nil {
+			remainingEntries = append(remainingEntries, entry)
+			continue
+		}
+		for _, platformEntry := range platformEntries {
+			platformPaths = append(platformPaths, filepath.Join(platformDir, platformEntry))
 		}
+	}
+
+	return remainingEntries, platformPaths
+}
 
-		// NOTE: only the last column can safely use ANSI color codes. Otherwise,
-		// column widths can be mismatched due to color codes used only in some
-		// rows.
-		// @see https://stackoverflow.com/questions/35398497/how-do-i-get-colors-to-work-with-golang-tabwriter
-		fmt.Fprintf(tabWriter, "%s\t%s\t%s\n", name, binary.Version, latestVersionInfo)
+func findBinaryPath(binaryName string, env goEnvironment, dirEntries [][]string) string {
+	for i, dir := range env.binDirs {
+		for _, entry := range dirEntries[i] {
+			if entry == binaryName || entry == binaryName+env.GOEXE {
+				return filepath.Join(dir, entry)
+			}
+		}
 	}
+
+	return env.newBinaryPath(binaryName)
 }
 
 func updateBinaries(
+	ctx context.Context,
 	introspectionResults []gobinaries.IntrospectionResult,
-	goCLI *gocli.GoCLI,
-	out io.Writer,
-	colorsFactory *colors.DecoratorFactory,
+	installer *installer,
+	smokeChecker SmokeChecker,
+	reporter Reporter,
 	options Options,
-) error {
-	var upgradeErrors []error
+	env goEnvironment,
+	fs FilesystemUtils,
+	summary *SummaryEvent,
+) {
 	var binariesToUpdate []gobinaries.GoBinary
-
-	fmt.Fprintln(out)
-
-	binaryNameFormatter := colorsFactory.NewDecorator(color.FgCyan)
-	faintFormatter := colorsFactory.NewDecorator(color.Faint)
+	targets := symlinkTargets(introspectionResults, fs)
 
 	for _, result := range introspectionResults {
 		if result.Error != nil {
 			continue
 		}
-		if !result.Binary.UpgradePossible() && !options.ForceReinstall {
+		binary := result.Binary
+		if binary.Held || targets[binary.Path] {
 			continue
 		}
 
-		if binary := result.Binary; binary.BuiltFromSource() {
-			verb := "reinstalling"
-			if result.Binary.UpgradePossible() {
-				verb = "upgrading"
-			}
-			fmt.Fprintf(out, "Skipping %s %s\n    ", verb, binaryNameFormatter(binary.Name))
-			if binary.BuiltWithGoBuild() {
-				fmt.Fprintf(out, "The binary was built from source (probably using \"%s\") and the binary path is unknown.\n",
-					faintFormatter("go build"))
-			} else {
-				fmt.Fprintf(out, "The binary was installed from source (probably using \"%s\" in the cloned repository).\n",
-					faintFormatter("go install"))
+		switch {
+		case binary.DowngradePossible() && (options.AllowDowngrade || binary.VersionRequested):
+		case binary.DowngradePossible() && options.ForceReinstall:
+			// NOTE: reinstall the current version instead of downgrading to the
+			// latest version.
+			binary.VersionQuery = binary.Version
+			binary.LatestVersion = binary.Version
+		case !binary.UpgradePossible() && !options.ForceReinstall:
+			continue
+		}
+
+		if binary.BuiltFromSource() {
+			reporter.Report(SkippedFromSourceEvent{Binary: binary})
+			reporter.Report(ProblemDetectedEvent{Binary: binary, Problem: binaryBuiltFromSourceProblem})
+			summary.Skipped++
+			continue
+		}
+
+		if binary.SymlinkTarget != "" && getSymlinkMode(options.Config.Binaries[binary.Name].Symlinks, options.Symlinks) == SymlinkModeSkip {
+			reporter.Report(SkippedSymlinkEvent{Binary: binary})
+			summary.Skipped++
+			continue
+		}
+
+		binariesToUpdate = append(binariesToUpdate, binary)
+	}
+
+	// NOTE: binaries are installed concurrently. The events of each binary are
+	// buffered and reported in order once the binary is installed, so the
+	// output of different binaries is not interleaved.
+	updates := make([]bufferedUpdate, len(binariesToUpdate))
+	for i := range updates {
+		updates[i].done = make(chan struct{})
+	}
+
+	go func() {
+		semaphore := make(chan struct{}, getJobs(options.Jobs))
+		for i, binary := range binariesToUpdate {
+			i, binary := i, binary
+
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
 			}
-			pathURL := binary.PathURL
-			if binary.BuiltWithGoBuild() {
-				// NOTE: binaries built with `go build` have `command-line-arguments`
-				// as their `path` which would not make sense in help message.
-				pathURL = "repositoryPath"
+			// NOTE: stop scheduling new installs once ctx is done.
+			if ctx.Err() != nil {
+				updates[i].canceled = true
+				close(updates[i].done)
+				continue
 			}
 
-			fmt.Fprintf(out, "    Install the binary using \"%s\" instead.\n",
-				faintFormatter(fmt.Sprintf("go install %s@latest", pathURL)))
-			fmt.Fprintf(out, "%s\n\n", binaryBuiltFromSourceProblem.String(colorsFactory))
-			continue
+			go func() {
+				defer close(updates[i].done)
+				defer func() { <-semaphore }()
+
+				updates[i].err = updateBinary(ctx, binary, installer, smokeChecker, &updates[i].events, options, env, fs)
+			}()
+		}
+	}()
+
+	for i, binary := range binariesToUpdate {
+		<-updates[i].done
+		updates[i].events.replay(reporter)
+
+		switch {
+		case updates[i].canceled:
+			summary.Canceled++
+		case updates[i].err != nil:
+			countFailed(summary, updates[i].err)
+		case binary.DowngradePossible():
+			summary.Downgraded++
+		case binary.UpgradePossible():
+			summary.Upgraded++
+		default:
+			summary.Reinstalled++
+		}
+	}
+}
+
+type bufferedUpdate struct {
+	events bufferedReporter
+	err    error
+	// canceled is set when the update was never started because ctx was done.
+	canceled bool
+	// done is closed once the update is finished.
+	done chan struct{}
+}
+
+func getJobs(jobs int) int {
+	if jobs > 0 {
+		return jobs
+	}
+
+	return runtime.GOMAXPROCS(0)
+}
+
+// updateBinary backs up, installs, and checks a single binary.
+func updateBinary(
+	ctx context.Context,
+	binary gobinaries.GoBinary,
+	installer *installer,
+	smokeChecker SmokeChecker,
+	reporter Reporter,
+	options Options,
+	env goEnvironment,
+	fs FilesystemUtils,
+) error {
+	binaryConfig := options.Config.Binaries[binary.Name]
+	installOptions, droppedSettings := getInstallOptions(binary, binaryConfig, env)
+	if options.InstallLatest {
+		installOptions.Version = binary.VersionQuery
+	}
+	reporter.Report(InstallStartedEvent{Binary: binary, BuildTags: installOptions.BuildTags})
+
+	var backupEntry *backup.Entry
+	if options.Backups != nil {
+		entry, err := options.Backups.Backup(binary.Path, binary.Name, binary.Version)
+		if err != nil {
+			err = fmt.Errorf("could not back up the binary: %w", err)
+			reporter.Report(InstallFinishedEvent{Binary: binary, Error: err})
+			return err
 		}
+		backupEntry = &entry
+	}
+
+	symlinkMode := getSymlinkMode(binaryConfig.Symlinks, options.Symlinks)
+	upgradeOutput, newSymlinkTarget, err := installer.install(ctx, binary, installOptions, symlinkMode)
+	reporter.Report(InstallFinishedEvent{
+		Binary:          binary,
+		Output:          upgradeOutput,
+		Error:           err,
+		DroppedSettings: droppedSettings,
+		SymlinkTarget:   newSymlinkTarget,
+	})
+
+	for _, problem := range FindCommonUpdateProblems(upgradeOutput) {
+		reporter.Report(ProblemDetectedEvent{Binary: binary, Problem: problem})
+	}
 
-		binariesToUpdate = append(binariesToUpdate, result.Binary)
+	if err == nil && len(binaryConfig.Check) > 0 {
+		var rollback func() error
+		switch {
+		case newSymlinkTarget != "":
+			// NOTE: the previous target is kept, so there is no need to
+			// restore the backup.
+			rollback = func() error { return retargetSymlink(binary.Path, binary.SymlinkTarget, fs) }
+		case backupEntry != nil:
+			rollback = func() error { return options.Backups.Restore(*backupEntry, resolvePath(fs, binary.Path)) }
+		}
+		err = runSmokeCheck(ctx, binary, binaryConfig.Check, smokeChecker, rollback, reporter)
 	}
 
-	if len(binariesToUpdate) == 0 {
+	return err
+}
+
+// runSmokeCheck runs the updated binary and rolls back the update if the
+// binary fails. The rollback is nil if the update cannot be rolled back.
+func runSmokeCheck(
+	ctx context.Context,
+	binary gobinaries.GoBinary,
+	args []string,
+	smokeChecker SmokeChecker,
+	rollback func() error,
+	reporter Reporter,
+) error {
+	output, err := smokeChecker.Check(ctx, binary.Path, args)
+	if err == nil {
 		return nil
 	}
 
-	latestVersionFormatter := colorsFactory.NewDecorator(color.FgGreen)
+	event := SmokeCheckFailedEvent{Binary: binary, Args: args, Output: output, Error: err}
+	if rollback != nil {
+		event.RollbackError = rollback()
+		event.RolledBack = event.RollbackError == nil
+	}
+	reporter.Report(event)
+
+	return fmt.Errorf("smoke check failed: %w", err)
+}
 
-	for _, binary := range binariesToUpdate {
-		var buildTagsInfo string
-		if len(binary.BuildTags) > 0 {
-			buildTagsInfo = fmt.Sprintf(" (build tags: %s)", faintFormatter(strings.Join(binary.BuildTags, ",")))
+func getOverrides(config config.Config) map[string]gobinaries.Override {
+	overrides := make(map[string]gobinaries.Override, len(config.Binaries))
+	for name, binaryConfig := range config.Binaries {
+		overrides[name] = gobinaries.Override{
+			Hold:         binaryConfig.Hold,
+			VersionQuery: binaryConfig.Version,
+			// NOTE: a version pinned in the config file is requested
+			// explicitly, so it is installed even if that is a downgrade.
+			VersionRequested: binaryConfig.Version != "",
+			PathURL:          binaryConfig.PathURL,
 		}
+	}
+
+	return overrides
+}
 
-		if binary.UpgradePossible() {
-			fmt.Fprintf(out, "Upgrading %s to %s%s ... ", binaryNameFormatter(binary.Name),
-				latestVersionFormatter(binary.LatestVersion), buildTagsInfo)
-		} else {
-			fmt.Fprintf(out, "Force-reinstalling %s %s%s ... ", binaryNameFormatter(binary.Name),
-				latestVersionFormatter(binary.LatestVersion), buildTagsInfo)
+// parseBinaryArgs splits the `name` and `name@version` arguments into binary
+// names and the requested version queries, keyed by binary names.
+func parseBinaryArgs(args []string) ([]string, map[string]string, error) {
+	binaryNames := make([]string, 0, len(args))
+	versionQueries := make(map[string]string)
+	for _, arg := range args {
+		binaryName, versionQuery, found := strings.Cut(arg, "@")
+		if binaryName == "" {
+			return nil, nil, fmt.Errorf("missing the binary name in %q", arg)
 		}
-		upgradeOutput, err := goCLI.UpgradePackage(binary.PathURL, binary.BuildTags)
-		if err != nil {
-			upgradeErrors = append(upgradeErrors, err)
-			fmt.Fprintln(out, "❌")
-			fmt.Fprintln(out, "    Could not install package")
-		} else {
-			fmt.Fprintln(out, "✅")
+		if found && versionQuery == "" {
+			return nil, nil, fmt.Errorf("missing the version after @ in %q", arg)
 		}
 
-		if len(upgradeOutput) > 0 && (options.Verbose || err != nil) {
-			fmt.Fprintln(out, upgradeOutput)
-
-			for _, problem := range FindCommonUpdateProblems(upgradeOutput) {
-				fmt.Fprintf(out, "%s\n", problem.String(colorsFactory))
-			}
+		binaryNames = append(binaryNames, binaryName)
+		if found {
+			versionQueries[binaryName] = versionQuery
 		}
-		fmt.Fprintln(out)
 	}
 
-	if len(upgradeErrors) > 0 {
-		return fmt.Errorf("could not install %s package(s)",
-			colorsFactory.NewDecorator(color.FgRed, color.Bold)(len(upgradeErrors)))
+	return binaryNames, versionQueries, nil
+}
+
+// withRequestedVersions applies the version queries requested on the command
+// line to the overrides. They take precedence over the config file.
+func withRequestedVersions(overrides map[string]gobinaries.Override, versionQueries map[string]string) map[string]gobinaries.Override {
+	for binaryName, versionQuery := range versionQueries {
+		override := overrides[binaryName]
+		// NOTE: requesting a version explicitly updates held binaries too.
+		override.Hold = false
+		override.VersionQuery = versionQuery
+		override.VersionRequested = true
+		overrides[binaryName] = override
 	}
 
-	return nil
+	return overrides
 }
 
-func getExecutableBinariesPath(cli *gocli.GoCLI) (string, error) {
-	gobin, err := cli.GetEnvVar("GOBIN")
-	if err != nil {
-		return "", nil
+// getInstallOptions returns the options that install the binary the same way
+// it was built: with the same build tags and build settings. It also returns
+// the build settings that cannot be replicated.
+func getInstallOptions(
+	binary gobinaries.GoBinary,
+	binaryConfig config.Binary,
+	env goEnvironment,
+) (gocli.InstallOptions, []debug.BuildSetting) {
+	buildTags := append([]string(nil), binary.BuildTags...)
+	for _, buildTag := range binaryConfig.BuildTags {
+		if !containsString(buildTags, buildTag) {
+			buildTags = append(buildTags, buildTag)
+		}
 	}
-	if len(gobin) > 0 {
-		return gobin, nil
+
+	// NOTE: install the exact version that was resolved and reported instead
+	// of resolving the version query again, which could install a version
+	// released in the meantime.
+	version := binary.LatestVersion
+	if version == "" {
+		version = binary.VersionQuery
 	}
 
-	gopath, err := cli.GetEnvVar("GOPATH")
-	if err != nil {
-		return "", nil
+	flags, settingsEnv, droppedSettings := gocli.BuildSettingsArgs(binary.Settings)
+	installOptions := gocli.InstallOptions{
+		// NOTE: install the binary into the directory it was found in, which
+		// may be the bin directory of a GOPATH entry other than the first one.
+		BinDir:    filepath.Dir(binary.Path),
+		Version:   version,
+		BuildTags: buildTags,
+		Flags:     flags,
+		// NOTE: the environment variables from the config file take precedence
+		// over the build settings of the binary.
+		Env: append(settingsEnv, binaryConfig.EnvList()...),
 	}
-	if len(gopath) == 0 {
-		return "", errors.New("GOPATH and GOPATH are not defined in 'go env' command")
+	if binary.CrossCompiled() {
+		installOptions.BinDir = ""
+		installOptions.Env = append(crossCompileEnv(binary, env), installOptions.Env...)
 	}
 
-	gobin = filepath.Join(gopath, "bin")
+	return installOptions, droppedSettings
+}
+
+// crossCompileEnv returns the environment variables that make `go install`
+// build the binary for its platform.
+//
+// `go install` refuses to install cross-compiled binaries when GOBIN is set,
+// also in the go env file. Instead, it installs them into the
+// `<goos>_<goarch>` subdirectory of the bin directory of the first GOPATH
+// entry, which is set by the installer. The go env file is ignored, so the
+// settings from it are passed explicitly.
+func crossCompileEnv(binary gobinaries.GoBinary, env goEnvironment) []string {
+	crossCompileEnv := []string{
+		"GOENV=off",
+		"GOBIN=",
+		"GOOS=" + binary.Setting("GOOS"),
+		"GOARCH=" + binary.Setting("GOARCH"),
+		// NOTE: keep using the same module cache, which is in the first GOPATH
+		// entry by default.
+		"GOMODCACHE=" + env.GOMODCACHE,
+	}
+
+	return append(crossCompileEnv, env.ModuleEnv()...)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
 
-	return gobin, nil
+	return false
 }
@@ -2,38 +2,148 @@ package updater
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Gelio/go-global-update/internal/colors"
+	"github.com/Gelio/go-global-update/internal/config"
 	"github.com/Gelio/go-global-update/internal/gobinaries"
 	"github.com/Gelio/go-global-update/internal/gobinariestest"
+	"github.com/Gelio/go-global-update/internal/gocli"
 	"github.com/Gelio/go-global-update/internal/goclitest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
-type mockFilesystemUtils struct{}
+type mockFilesystemUtils struct {
+	// symlinks map the paths of symlinks to their targets.
+	symlinks map[string]string
+	// modes map paths to their file modes. Other paths are regular
+	// executable files.
+	modes map[string]os.FileMode
+}
+
+func (_ mockFilesystemUtils) MkdirAll(_ string) error {
+	return nil
+}
+
+func (_ mockFilesystemUtils) MkdirTemp(dir, pattern string) (string, error) {
+	return filepath.Join(dir, pattern+"0"), nil
+}
+
+func (_ mockFilesystemUtils) Remove(_ string) error {
+	return nil
+}
+
+func (_ mockFilesystemUtils) RemoveAll(_ string) error {
+	return nil
+}
+
+func (_ mockFilesystemUtils) Rename(_, _ string) error {
+	return nil
+}
 
-func (_ mockFilesystemUtils) Chdir(_ string) error {
+func (fs mockFilesystemUtils) EvalSymlinks(path string) (string, error) {
+	if target, ok := fs.symlinks[path]; ok {
+		return target, nil
+	}
+
+	return path, nil
+}
+
+func (fs mockFilesystemUtils) Readlink(path string) (string, error) {
+	if target, ok := fs.symlinks[path]; ok {
+		return target, nil
+	}
+
+	return "", fmt.Errorf("%s is not a symlink", path)
+}
+
+func (_ mockFilesystemUtils) Symlink(_, _ string) error {
 	return nil
 }
 
+func (fs mockFilesystemUtils) Stat(path string) (os.FileInfo, error) {
+	mode, ok := fs.modes[path]
+	if !ok {
+		mode = 0o755
+	}
+
+	return mockFileInfo{name: filepath.Base(path), mode: mode}, nil
+}
+
+type mockFileInfo struct {
+	name string
+	mode os.FileMode
+}
+
+func (fi mockFileInfo) Name() string       { return fi.name }
+func (fi mockFileInfo) Size() int64        { return 0 }
+func (fi mockFileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi mockFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi mockFileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi mockFileInfo) Sys() interface{}   { return nil }
+
+type mockSmokeChecker struct {
+	output string
+	err    error
+}
+
+func (c *mockSmokeChecker) Check(_ context.Context, _ string, _ []string) (string, error) {
+	return c.output, c.err
+}
+
 func updateMockResponse(binary gobinaries.GoBinary, output string, err error) goclitest.MockResponse {
 	return goclitest.MockResponse{
-		Args:   []string{"install", fmt.Sprintf("%s@latest", binary.PathURL)},
+		Args:   []string{"install", fmt.Sprintf("%s@%s", binary.PathURL, binary.LatestVersion)},
 		Output: output,
 		Error:  err,
 	}
 }
 
-func gobinMockResponse() goclitest.MockResponse {
-	return goclitest.MockResponse{
-		Args:   []string{"env", "GOBIN"},
-		Output: gobinariestest.GOBIN,
+// stagedBuildInfoMockResponse returns the build information of the binary
+// installed into the staging directory, which matches the plan.
+func stagedBuildInfoMockResponse(binary gobinaries.GoBinary) goclitest.MockResponse {
+	stagingDir := filepath.Join(filepath.Dir(binary.Path), ".go-global-update-0")
+	execName := gobinaries.ExecName(binary.InstallPath())
+
+	return goclitest.GetModuleInfoMockResponse(stagingDir, execName, buildInfoOutput(filepath.Join(stagingDir, execName), binary))
+}
+
+// buildInfoOutput returns the `go version -m` output of the binary with its
+// latest version and build tags.
+func buildInfoOutput(path string, binary gobinaries.GoBinary) string {
+	output := fmt.Sprintf(
+		"%s: go1.22.0\n\tpath\t%s\n\tmod\t%s\t%s\n",
+		path, binary.InstallPath(), binary.InstallModule(), binary.LatestVersion,
+	)
+	if len(binary.BuildTags) > 0 {
+		output += fmt.Sprintf("\tbuild\t-tags=%s\n", strings.Join(binary.BuildTags, ","))
 	}
+
+	return output
+}
+
+func onceMockResponse(response goclitest.MockResponse) goclitest.MockResponse {
+	response.Once = true
+	return response
+}
+
+func withLatestVersion(binary gobinaries.GoBinary, latestVersion string) gobinaries.GoBinary {
+	binary.LatestVersion = latestVersion
+	return binary
+}
+
+func gobinMockResponse() goclitest.MockResponse {
+	return goclitest.GetGoEnvMockResponse(gocli.GoEnv{GOBIN: gobinariestest.GOBIN})
 }
 
 func TestUpdateAllFoundBinaries(t *testing.T) {
@@ -54,30 +164,363 @@ func TestUpdateAllFoundBinaries(t *testing.T) {
 			gobinariestest.GetLatestVersionMockResponse(gofumptMockBinary.Binary),
 			gobinariestest.GetModuleInfoMockResponse(gofumptMockBinary),
 			updateMockResponse(gofumptMockBinary.Binary, "", nil),
+			stagedBuildInfoMockResponse(gofumptMockBinary.Binary),
 			gobinariestest.GetLatestVersionMockResponse(shfmtMockBinary.Binary),
 			gobinariestest.GetModuleInfoMockResponse(shfmtMockBinary),
 			updateMockResponse(shfmtMockBinary.Binary, "", nil),
+			stagedBuildInfoMockResponse(shfmtMockBinary.Binary),
+		},
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := mockFilesystemUtils{}
+	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
+
+	err := UpdateBinaries(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, fsutils, &mockSmokeChecker{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, strings.TrimSpace(`
+Binary       Current version      Status
+gofumpt      v0.3.0               can upgrade to v0.4.0 (minor)
+shfmt        v3.4.2               can upgrade to v3.4.3 (patch)
+
+Upgrading gofumpt to v0.4.0 ... ✅
+
+Upgrading shfmt to v3.4.3 ... ✅
+
+`), strings.TrimSpace(output.String()))
+}
+
+func TestUpdateBinariesFromAllGOPATHEntries(t *testing.T) {
+	firstBinDir := filepath.Join("/home", "test", "go", "bin")
+	secondBinDir := filepath.Join("/home", "test", "other", "bin")
+	gofumptMockBinary := gobinariestest.GetGofumptMockBinary()
+	gofumptMockBinary.Binary.LatestVersion = "v0.4.0"
+	shfmtMockBinary := gobinariestest.GetShfmtMockBinary()
+	shfmtMockBinary.Binary.LatestVersion = "v3.4.3"
+	shfmtMockBinary.Binary.Path = filepath.Join(secondBinDir, shfmtMockBinary.Binary.Name)
+
+	logger := zap.NewNop()
+	options := Options{}
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		EntriesByDir: map[string][]string{
+			firstBinDir:  {gofumptMockBinary.Binary.Name},
+			secondBinDir: {shfmtMockBinary.Binary.Name},
+		},
+	}
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			goclitest.GetGoEnvMockResponse(gocli.GoEnv{
+				GOPATH: strings.Join([]string{filepath.Dir(firstBinDir), filepath.Dir(secondBinDir)}, string(filepath.ListSeparator)),
+			}),
+			gobinariestest.GetLatestVersionMockResponse(gofumptMockBinary.Binary),
+			goclitest.GetModuleInfoMockResponse(firstBinDir, gofumptMockBinary.Binary.Name, gofumptMockBinary.ModuleInfo),
+			{
+				Args: []string{"install", "mvdan.cc/gofumpt@v0.4.0"},
+				Env:  []string{"GOWORK=off", "GOBIN=" + filepath.Join(firstBinDir, ".go-global-update-0")},
+			},
+			stagedBuildInfoMockResponse(gofumptMockBinary.Binary),
+			gobinariestest.GetLatestVersionMockResponse(shfmtMockBinary.Binary),
+			goclitest.GetModuleInfoMockResponse(secondBinDir, shfmtMockBinary.Binary.Name, shfmtMockBinary.ModuleInfo),
+			{
+				// NOTE: the binary is installed back into the directory it was
+				// found in.
+				Args: []string{"install", "mvdan.cc/sh/v3/cmd/shfmt@v3.4.3"},
+				Env:  []string{"GOWORK=off", "GOBIN=" + filepath.Join(secondBinDir, ".go-global-update-0")},
+			},
+			stagedBuildInfoMockResponse(shfmtMockBinary.Binary),
 		},
 	}
 
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
 	fsutils := mockFilesystemUtils{}
 	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
 
-	err := UpdateBinaries(logger, options, &output, &colorsFactory, &cmdRunner, &lister, fsutils)
+	err := UpdateBinaries(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, fsutils, &mockSmokeChecker{})
 
 	assert.Nil(t, err)
 	assert.Equal(t, strings.TrimSpace(`
 Binary       Current version      Status
-gofumpt      v0.3.0               can upgrade to v0.4.0
-shfmt        v3.4.2               can upgrade to v3.4.3
+gofumpt      v0.3.0               can upgrade to v0.4.0 (minor)
+shfmt        v3.4.2               can upgrade to v3.4.3 (patch)
 
 Upgrading gofumpt to v0.4.0 ... ✅
 
 Upgrading shfmt to v3.4.3 ... ✅
+`), strings.TrimSpace(output.String()))
+}
+
+func TestUpdateBinariesInAdditionalDirs(t *testing.T) {
+	extraDir := filepath.Join("/home", "test", ".local", "bin")
+	shfmtMockBinary := gobinariestest.GetShfmtMockBinary()
+	shfmtMockBinary.Binary.LatestVersion = "v3.4.3"
+	shfmtMockBinary.Binary.Path = filepath.Join(extraDir, shfmtMockBinary.Binary.Name)
+
+	logger := zap.NewNop()
+	options := Options{Dirs: []string{extraDir, gobinariestest.GOBIN}}
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		EntriesByDir: map[string][]string{
+			extraDir: {shfmtMockBinary.Binary.Name},
+		},
+	}
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinMockResponse(),
+			gobinariestest.GetLatestVersionMockResponse(shfmtMockBinary.Binary),
+			goclitest.GetModuleInfoMockResponse(extraDir, shfmtMockBinary.Binary.Name, shfmtMockBinary.ModuleInfo),
+			{
+				Args: []string{"install", "mvdan.cc/sh/v3/cmd/shfmt@v3.4.3"},
+				Env:  []string{"GOWORK=off", "GOBIN=" + filepath.Join(extraDir, ".go-global-update-0")},
+				Dir:  gobinariestest.GOBIN,
+			},
+			stagedBuildInfoMockResponse(shfmtMockBinary.Binary),
+		},
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := mockFilesystemUtils{}
+	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
+
+	err := UpdateBinaries(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, fsutils, &mockSmokeChecker{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, strings.TrimSpace(`
+Binary      Current version      Status
+shfmt       v3.4.2               can upgrade to v3.4.3 (patch)
 
+Upgrading shfmt to v3.4.3 ... ✅
 `), strings.TrimSpace(output.String()))
 }
 
+func TestUpdateCrossCompiledBinaries(t *testing.T) {
+	otherGOPATH := filepath.Join("/home", "test", "other")
+	modCache := filepath.Join("/home", "test", "go", "pkg", "mod")
+	linuxDir := filepath.Join(gobinariestest.GOBIN, "linux_arm64")
+	windowsDir := filepath.Join(otherGOPATH, "bin", "windows_amd64")
+	linuxStagingDir := filepath.Join(linuxDir, ".go-global-update-0")
+	windowsStagingDir := filepath.Join(windowsDir, ".go-global-update-0")
+	goProxy := "https://proxy.example.com"
+	shfmtMockBinary := gobinariestest.GetShfmtMockBinary()
+	shfmtMockBinary.Binary.LatestVersion = "v3.4.3"
+	crossCompiledModuleInfo := func(goos, goarch string) string {
+		return shfmtMockBinary.ModuleInfo + fmt.Sprintf("        build   GOOS=%s\n        build   GOARCH=%s\n", goos, goarch)
+	}
+
+	logger := zap.NewNop()
+	options := Options{}
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		EntriesByDir: map[string][]string{
+			gobinariestest.GOBIN:              {shfmtMockBinary.Binary.Name, "linux_arm64"},
+			linuxDir:                          {shfmtMockBinary.Binary.Name},
+			filepath.Join(otherGOPATH, "bin"): {"windows_amd64"},
+			windowsDir:                        {shfmtMockBinary.Binary.Name + ".exe"},
+		},
+	}
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			goclitest.GetGoEnvMockResponse(gocli.GoEnv{
+				GOPATH:     strings.Join([]string{filepath.Dir(gobinariestest.GOBIN), otherGOPATH}, string(filepath.ListSeparator)),
+				GOMODCACHE: modCache,
+				GOPROXY:    goProxy,
+			}),
+			gobinariestest.GetLatestVersionMockResponse(shfmtMockBinary.Binary),
+			gobinariestest.GetModuleInfoMockResponse(shfmtMockBinary),
+			onceMockResponse(goclitest.GetModuleInfoMockResponse(linuxDir, shfmtMockBinary.Binary.Name, crossCompiledModuleInfo("linux", "arm64"))),
+			onceMockResponse(goclitest.GetModuleInfoMockResponse(windowsDir, shfmtMockBinary.Binary.Name+".exe", crossCompiledModuleInfo("windows", "amd64"))),
+			{
+				Args: []string{"install", "mvdan.cc/sh/v3/cmd/shfmt@v3.4.3"},
+				Env:  []string{"GOWORK=off", "GOBIN=" + filepath.Join(gobinariestest.GOBIN, ".go-global-update-0")},
+			},
+			stagedBuildInfoMockResponse(shfmtMockBinary.Binary),
+			// NOTE: cross-compiled binaries are installed into the bin
+			// directory of a temporary GOPATH. The go env file is ignored, so
+			// a GOBIN set in it does not prevent installing them.
+			{
+				Args: []string{"install", "mvdan.cc/sh/v3/cmd/shfmt@v3.4.3"},
+				Env: []string{"GOWORK=off", "GOENV=off", "GOBIN=", "GOOS=linux", "GOARCH=arm64", "GOMODCACHE=" + modCache,
+					"GOPROXY=" + goProxy, "GOPATH=" + linuxStagingDir},
+			},
+			goclitest.GetModuleInfoMockResponse(filepath.Join(linuxStagingDir, "bin", "linux_arm64"), shfmtMockBinary.Binary.Name,
+				buildInfoOutput(filepath.Join(linuxStagingDir, "bin", "linux_arm64", shfmtMockBinary.Binary.Name), shfmtMockBinary.Binary)),
+			{
+				Args: []string{"install", "mvdan.cc/sh/v3/cmd/shfmt@v3.4.3"},
+				Env: []string{"GOWORK=off", "GOENV=off", "GOBIN=", "GOOS=windows", "GOARCH=amd64", "GOMODCACHE=" + modCache,
+					"GOPROXY=" + goProxy, "GOPATH=" + windowsStagingDir},
+			},
+			goclitest.GetModuleInfoMockResponse(filepath.Join(windowsStagingDir, "bin", "windows_amd64"), shfmtMockBinary.Binary.Name+".exe",
+				buildInfoOutput(filepath.Join(windowsStagingDir, "bin", "windows_amd64", shfmtMockBinary.Binary.Name+".exe"), shfmtMockBinary.Binary)),
+		},
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := movingFilesystemUtils{}
+	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
+
+	err := UpdateBinaries(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, &fsutils, &mockSmokeChecker{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		fmt.Sprintf("rename %s %s", filepath.Join(gobinariestest.GOBIN, ".go-global-update-0", shfmtMockBinary.Binary.Name), shfmtMockBinary.Binary.Path),
+		fmt.Sprintf("rename %s %s", filepath.Join(linuxStagingDir, "bin", "linux_arm64", shfmtMockBinary.Binary.Name), filepath.Join(linuxDir, shfmtMockBinary.Binary.Name)),
+		fmt.Sprintf("rename %s %s", filepath.Join(windowsStagingDir, "bin", "windows_amd64", shfmtMockBinary.Binary.Name+".exe"), filepath.Join(windowsDir, shfmtMockBinary.Binary.Name+".exe")),
+	}, fsutils.operations)
+	assert.Equal(t, strings.TrimSpace(`
+Binary                     Current version      Status
+shfmt                      v3.4.2               can upgrade to v3.4.3 (patch)
+shfmt (linux/arm64)        v3.4.2               can upgrade to v3.4.3 (patch)
+shfmt (windows/amd64)      v3.4.2               can upgrade to v3.4.3 (patch)
+
+Upgrading shfmt to v3.4.3 ... ✅
+
+Upgrading shfmt (linux/arm64) to v3.4.3 ... ✅
+
+Upgrading shfmt (windows/amd64) to v3.4.3 ... ✅
+`), strings.TrimSpace(output.String()))
+}
+
+func TestReplicateBuildSettings(t *testing.T) {
+	shfmtMockBinary := gobinariestest.GetShfmtMockBinary()
+	shfmtMockBinary.Binary.LatestVersion = "v3.4.3"
+	shfmtMockBinary.ModuleInfo += `        build   -buildmode=exe
+        build   -compiler=gc
+        build   -ldflags="-s -w -X main.version=v3.4.2"
+        build   -trimpath=true
+        build   CGO_ENABLED=0
+        build   GOARCH=amd64
+        build   GOOS=linux
+        build   GOAMD64=v3
+`
+
+	logger := zap.NewNop()
+	options := Options{}
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		Entries: []string{shfmtMockBinary.Binary.Name},
+	}
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinMockResponse(),
+			gobinariestest.GetLatestVersionMockResponse(shfmtMockBinary.Binary),
+			gobinariestest.GetModuleInfoMockResponse(shfmtMockBinary),
+			{
+				Args: []string{"install", "-ldflags=-s -w", "-trimpath", "mvdan.cc/sh/v3/cmd/shfmt@v3.4.3"},
+				Env:  []string{"GOWORK=off", "GOBIN=" + filepath.Join(gobinariestest.GOBIN, ".go-global-update-0"), "CGO_ENABLED=0", "GOAMD64=v3"},
+			},
+			stagedBuildInfoMockResponse(shfmtMockBinary.Binary),
+		},
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := mockFilesystemUtils{}
+	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
+
+	err := UpdateBinaries(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, fsutils, &mockSmokeChecker{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, strings.TrimSpace(`
+Binary      Current version      Status
+shfmt       v3.4.2               can upgrade to v3.4.3 (patch)
+
+Upgrading shfmt to v3.4.3 ... ✅
+    Warning: could not replicate the build setting -ldflags=-X main.version=v3.4.2
+`), strings.TrimSpace(output.String()))
+}
+
+func TestClassifyNonGoEntries(t *testing.T) {
+	shfmtMockBinary := gobinariestest.GetShfmtMockBinary()
+
+	for _, ignoreNonGo := range []bool{false, true} {
+		logger := zap.NewNop()
+		options := Options{DryRun: true, IgnoreNonGo: ignoreNonGo}
+		var output bytes.Buffer
+		lister := gobinariestest.TestSuccessDirectoryLister{
+			Entries: []string{shfmtMockBinary.Binary.Name, "script.sh", "old-tool", "secret", "missing"},
+		}
+		cmdRunner := goclitest.TestGoCmdRunner{
+			Responses: []goclitest.MockResponse{
+				gobinMockResponse(),
+				gobinariestest.GetLatestVersionMockResponse(shfmtMockBinary.Binary),
+				gobinariestest.GetModuleInfoMockResponse(shfmtMockBinary),
+				{
+					Args:   []string{"version", "-m", filepath.Join(gobinariestest.GOBIN, "script.sh")},
+					Output: "script.sh: could not read Go build info from script.sh: unrecognized file format",
+					Error:  errors.New("exit status 1"),
+				},
+				goclitest.GetModuleInfoMockResponse(gobinariestest.GOBIN, "old-tool", "old-tool: go1.12"),
+				{
+					Args:   []string{"version", "-m", filepath.Join(gobinariestest.GOBIN, "secret")},
+					Output: "open secret: permission denied",
+					Error:  errors.New("exit status 1"),
+				},
+				{
+					Args:   []string{"version", "-m", filepath.Join(gobinariestest.GOBIN, "missing")},
+					Output: "missing: unexpected error",
+					Error:  errors.New("exit status 1"),
+				},
+			},
+		}
+
+		buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+		fsutils := mockFilesystemUtils{}
+		colorsFactory := colors.NewFactory(false)
+		reporter := NewTextReporter(&output, &colorsFactory, false)
+
+		err := UpdateBinaries(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, fsutils, &mockSmokeChecker{})
+
+		assert.Nil(t, err)
+		if ignoreNonGo {
+			assert.Equal(t, strings.TrimSpace(`
+could not introspect binary missing: could not get module info about /home/test/go/bin/missing: could not retrieve version information about binary /home/test/go/bin/missing: exit status 1
+missing: unexpected error
+Binary      Current version      Status
+shfmt       v3.4.2               up-to-date
+`), strings.TrimSpace(output.String()))
+		} else {
+			assert.Equal(t, strings.TrimSpace(`
+could not introspect binary missing: could not get module info about /home/test/go/bin/missing: could not retrieve version information about binary /home/test/go/bin/missing: exit status 1
+missing: unexpected error
+Binary         Current version      Status
+shfmt          v3.4.2               up-to-date
+script.sh      -                    not a Go binary
+old-tool       -                    no build info (stripped or GOPATH mode)
+secret         -                    permission denied
+`), strings.TrimSpace(output.String()))
+		}
+	}
+}
+
+func TestReportGoEnvErrors(t *testing.T) {
+	logger := zap.NewNop()
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{}
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			{
+				Args:   append([]string{"env", "-json"}, gocli.GoEnvVariables...),
+				Output: "go: unknown GOEXPERIMENT foo",
+				Error:  errors.New("exit status 1"),
+			},
+		},
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := mockFilesystemUtils{}
+	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
+
+	err := UpdateBinaries(context.Background(), logger, Options{}, &reporter, &cmdRunner, &lister, &buildInfoReader, fsutils, &mockSmokeChecker{})
+
+	assert.EqualError(t, err, "could not read the go environment: exit status 1: go: unknown GOEXPERIMENT foo")
+}
+
 func TestSkipUpgradingBuiltFromSource(t *testing.T) {
 	builtFromSourceMockBinary := gobinariestest.MockBinary{
 		Binary: gobinaries.GoBinary{
@@ -130,10 +573,12 @@ installed-from-source: go1.17
 		},
 	}
 
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
 	fsutils := mockFilesystemUtils{}
 	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
 
-	err := UpdateBinaries(logger, options, &output, &colorsFactory, &cmdRunner, &lister, fsutils)
+	err := UpdateBinaries(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, fsutils, &mockSmokeChecker{})
 
 	assert.Nil(t, err)
 	assert.Equal(t, strings.TrimSpace(`
@@ -177,22 +622,26 @@ func TestForceReinstallingBinaries(t *testing.T) {
 			gobinariestest.GetLatestVersionMockResponse(gofumptMockBinary.Binary),
 			gobinariestest.GetModuleInfoMockResponse(gofumptMockBinary),
 			updateMockResponse(gofumptMockBinary.Binary, "", nil),
+			stagedBuildInfoMockResponse(gofumptMockBinary.Binary),
 			gobinariestest.GetLatestVersionMockResponse(shfmtMockBinary.Binary),
 			gobinariestest.GetModuleInfoMockResponse(shfmtMockBinary),
 			updateMockResponse(shfmtMockBinary.Binary, "", nil),
+			stagedBuildInfoMockResponse(shfmtMockBinary.Binary),
 		},
 	}
 
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
 	fsutils := mockFilesystemUtils{}
 	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
 
-	err := UpdateBinaries(logger, options, &output, &colorsFactory, &cmdRunner, &lister, fsutils)
+	err := UpdateBinaries(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, fsutils, &mockSmokeChecker{})
 
 	assert.Nil(t, err)
 	assert.Equal(t, strings.TrimSpace(`
 Binary       Current version      Status
 gofumpt      v0.3.0               up-to-date
-shfmt        v3.4.2               can upgrade to v3.4.3
+shfmt        v3.4.2               can upgrade to v3.4.3 (patch)
 
 Force-reinstalling gofumpt v0.3.0 ... ✅
 
@@ -222,24 +671,630 @@ func TestPreserveBuildTags(t *testing.T) {
 			gobinariestest.GetLatestVersionMockResponse(shfmtMockBinary.Binary),
 			gobinariestest.GetModuleInfoMockResponse(shfmtMockBinary),
 			{
-				Args:   []string{"install", "-tags", "a,b,c", fmt.Sprintf("%s@latest", shfmtMockBinary.Binary.PathURL)},
+				Args:   []string{"install", "-tags", "a,b,c", fmt.Sprintf("%s@v3.4.3", shfmtMockBinary.Binary.PathURL)},
 				Output: "",
 				Error:  nil,
 			},
+			stagedBuildInfoMockResponse(shfmtMockBinary.Binary),
 		},
 	}
 
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
 	fsutils := mockFilesystemUtils{}
 	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
 
-	err := UpdateBinaries(logger, options, &output, &colorsFactory, &cmdRunner, &lister, fsutils)
+	err := UpdateBinaries(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, fsutils, &mockSmokeChecker{})
 
 	assert.Nil(t, err)
 	assert.Equal(t, strings.TrimSpace(`
 Binary      Current version      Status
-shfmt       v3.4.2               can upgrade to v3.4.3
+shfmt       v3.4.2               can upgrade to v3.4.3 (patch)
 
 Upgrading shfmt to v3.4.3 (build tags: a,b,c) ... ✅
 
 `), strings.TrimSpace(output.String()))
 }
+
+func TestJSONOutput(t *testing.T) {
+	gofumptMockBinary := gobinariestest.GetGofumptMockBinary()
+	shfmtMockBinary := gobinariestest.GetShfmtMockBinary()
+	shfmtMockBinary.Binary.LatestVersion = "v3.4.3"
+	shfmtUpdateOutput := "go install: mvdan.cc/sh/v3/cmd/shfmt@latest: module mvdan.cc/sh/v3@latest found (v3.4.3), but does not contain package mvdan.cc/sh/v3/cmd/shfmt"
+
+	logger := zap.NewNop()
+	options := Options{}
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		Entries: []string{gofumptMockBinary.Binary.Name, shfmtMockBinary.Binary.Name, "not-a-go-binary"},
+	}
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinMockResponse(),
+			gobinariestest.GetLatestVersionMockResponse(gofumptMockBinary.Binary),
+			gobinariestest.GetModuleInfoMockResponse(gofumptMockBinary),
+			gobinariestest.GetLatestVersionMockResponse(shfmtMockBinary.Binary),
+			gobinariestest.GetModuleInfoMockResponse(shfmtMockBinary),
+			updateMockResponse(shfmtMockBinary.Binary, shfmtUpdateOutput, fmt.Errorf("exit status 1")),
+		},
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := mockFilesystemUtils{}
+	reporter := NewJSONReporter(&output)
+
+	err := UpdateBinaries(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, fsutils, &mockSmokeChecker{})
+	assert.NotNil(t, err)
+
+	var report Report
+	require.Nil(t, json.Unmarshal(output.Bytes(), &report), "output is not valid JSON: %s", output.String())
+	require.Len(t, report.Binaries, 3)
+	assert.False(t, report.DryRun)
+
+	assert.Equal(t, BinaryReport{
+		Name:           "gofumpt",
+		PathURL:        "mvdan.cc/gofumpt",
+		Module:         "mvdan.cc/gofumpt",
+		CurrentVersion: "v0.3.0",
+		LatestVersion:  "v0.3.0",
+		Status:         BinaryStatusUpToDate,
+		UpgradeState:   gobinaries.UpgradeStateEqual,
+	}, report.Binaries[0])
+
+	assert.Equal(t, BinaryReport{
+		Name:           "shfmt",
+		PathURL:        "mvdan.cc/sh/v3/cmd/shfmt",
+		Module:         "mvdan.cc/sh/v3",
+		CurrentVersion: "v3.4.2",
+		LatestVersion:  "v3.4.3",
+		Status:         BinaryStatusUpgradable,
+		UpgradeState:   gobinaries.UpgradeStatePatchBehind,
+		Update: &UpdateReport{
+			Outcome:  UpdateOutcomeFailed,
+			Output:   shfmtUpdateOutput,
+			Problems: []string{"E002"},
+		},
+	}, report.Binaries[1])
+
+	assert.Equal(t, "not-a-go-binary", report.Binaries[2].Name)
+	assert.Equal(t, BinaryStatusError, report.Binaries[2].Status)
+	assert.NotEmpty(t, report.Binaries[2].Error)
+}
+
+type recordingReporter struct {
+	events []Event
+}
+
+func (r *recordingReporter) Report(event Event) {
+	r.events = append(r.events, event)
+}
+
+func TestReportEvents(t *testing.T) {
+	gofumptMockBinary := gobinariestest.GetGofumptMockBinary()
+	gofumptMockBinary.Binary.LatestVersion = "v0.4.0"
+	gofumptUpdateOutput := "go: downloading mvdan.cc/gofumpt v0.4.0"
+
+	logger := zap.NewNop()
+	options := Options{}
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		Entries: []string{gofumptMockBinary.Binary.Name},
+	}
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinMockResponse(),
+			gobinariestest.GetLatestVersionMockResponse(gofumptMockBinary.Binary),
+			gobinariestest.GetModuleInfoMockResponse(gofumptMockBinary),
+			updateMockResponse(gofumptMockBinary.Binary, gofumptUpdateOutput, nil),
+			stagedBuildInfoMockResponse(gofumptMockBinary.Binary),
+		},
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := mockFilesystemUtils{}
+	reporter := recordingReporter{}
+
+	err := UpdateBinaries(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, fsutils, &mockSmokeChecker{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []Event{
+		IntrospectedEvent{Results: []gobinaries.IntrospectionResult{{Binary: gofumptMockBinary.Binary}}},
+		InstallStartedEvent{Binary: gofumptMockBinary.Binary},
+		InstallFinishedEvent{Binary: gofumptMockBinary.Binary, Output: gofumptUpdateOutput},
+		SummaryEvent{Upgraded: 1},
+	}, reporter.events)
+}
+
+func TestConfigOverrides(t *testing.T) {
+	gofumptMockBinary := gobinariestest.GetGofumptMockBinary()
+	shfmtMockBinary := gobinariestest.GetShfmtMockBinary()
+	shfmtMockBinary.Binary.BuildTags = []string{"a"}
+	shfmtMockBinary.ModuleInfo = fmt.Sprintf(`%s
+  build  -tags=a`, shfmtMockBinary.ModuleInfo)
+	goimportsMockBinary := gobinariestest.GetGoimportsMockBinary()
+
+	logger := zap.NewNop()
+	options := Options{
+		Config: config.Config{
+			Binaries: map[string]config.Binary{
+				"gofumpt": {
+					Hold: true,
+				},
+				"shfmt": {
+					Version:   "v3.5",
+					BuildTags: []string{"a", "netgo"},
+					Env:       map[string]string{"CGO_ENABLED": "0"},
+				},
+				"goimports": {
+					PathURL: "golang.org/x/tools/gopls/cmd/goimports",
+				},
+			},
+		},
+	}
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		Entries: []string{gofumptMockBinary.Binary.Name, shfmtMockBinary.Binary.Name, goimportsMockBinary.Binary.Name},
+	}
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinMockResponse(),
+			gobinariestest.GetModuleInfoMockResponse(gofumptMockBinary),
+			goclitest.GetLatestVersionMockResponse(gofumptMockBinary.Binary.ModuleURL, "v0.4.0"),
+			gobinariestest.GetModuleInfoMockResponse(shfmtMockBinary),
+			goclitest.GetVersionQueryMockResponse(shfmtMockBinary.Binary.ModuleURL, "v3.5", "v3.5.1"),
+			{
+				Args: []string{"install", "-tags", "a,netgo", "mvdan.cc/sh/v3/cmd/shfmt@v3.5.1"},
+				// NOTE: go commands run in GOBIN, outside of any workspace
+				Env: []string{"GOWORK=off", "GOBIN=" + filepath.Join(gobinariestest.GOBIN, ".go-global-update-0"), "CGO_ENABLED=0"},
+				Dir: gobinariestest.GOBIN,
+			},
+			stagedBuildInfoMockResponse(gobinaries.GoBinary{
+				Path:          shfmtMockBinary.Binary.Path,
+				PathURL:       shfmtMockBinary.Binary.PathURL,
+				ModuleURL:     shfmtMockBinary.Binary.ModuleURL,
+				LatestVersion: "v3.5.1",
+				BuildTags:     []string{"a", "netgo"},
+			}),
+			gobinariestest.GetModuleInfoMockResponse(goimportsMockBinary),
+			{
+				Args:  []string{"list", "-m", "-f", "{{.Version}}", "golang.org/x/tools/gopls/cmd/goimports@v0.9.0"},
+				Error: fmt.Errorf("exit status 1"),
+			},
+			{
+				Args:  []string{"list", "-m", "-f", "{{.Version}}", "golang.org/x/tools/gopls/cmd@latest"},
+				Error: fmt.Errorf("exit status 1"),
+			},
+			goclitest.GetLatestVersionMockResponse("golang.org/x/tools/gopls", "v0.9.0"),
+			{
+				Args: []string{"install", "golang.org/x/tools/gopls/cmd/goimports@v0.9.0"},
+			},
+			stagedBuildInfoMockResponse(gobinaries.GoBinary{
+				PathURL:       "golang.org/x/tools/gopls/cmd/goimports",
+				ModuleURL:     "golang.org/x/tools/gopls",
+				Path:          goimportsMockBinary.Binary.Path,
+				LatestVersion: "v0.9.0",
+			}),
+		},
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := mockFilesystemUtils{}
+	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
+
+	err := UpdateBinaries(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, fsutils, &mockSmokeChecker{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, strings.TrimSpace(`
+Binary         Current version      Status
+gofumpt        v0.3.0               held (can upgrade to v0.4.0 (minor))
+shfmt          v3.4.2               can upgrade to v3.5.1 (minor, requested v3.5)
+goimports      v0.1.12              can upgrade to v0.9.0 from golang.org/x/tools/gopls/cmd/goimports
+
+Upgrading shfmt to v3.5.1 (build tags: a,netgo) ... ✅
+
+Upgrading goimports to v0.9.0 ... ✅
+
+`), strings.TrimSpace(output.String()))
+}
+
+func TestRequestedVersions(t *testing.T) {
+	gofumptMockBinary := gobinariestest.GetGofumptMockBinary()
+	gofumptMockBinary.Binary.LatestVersion = "v0.4.1-0.20240105120000-0123456789ab"
+	shfmtMockBinary := gobinariestest.GetShfmtMockBinary()
+	shfmtMockBinary.Binary.BuildTags = []string{"netgo"}
+	shfmtMockBinary.Binary.LatestVersion = "v3.4.1"
+	shfmtMockBinary.ModuleInfo = fmt.Sprintf(`%s
+  build  -tags=netgo`, shfmtMockBinary.ModuleInfo)
+
+	logger := zap.NewNop()
+	options := Options{
+		BinariesToUpdate: []string{"gofumpt@master", "shfmt@v3.4.1"},
+		Config: config.Config{
+			Binaries: map[string]config.Binary{
+				"gofumpt": {
+					Hold: true,
+				},
+			},
+		},
+	}
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		Entries: []string{gofumptMockBinary.Binary.Name, shfmtMockBinary.Binary.Name},
+	}
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinMockResponse(),
+			gobinariestest.GetModuleInfoMockResponse(gofumptMockBinary),
+			goclitest.GetVersionQueryMockResponse(gofumptMockBinary.Binary.ModuleURL, "master", gofumptMockBinary.Binary.LatestVersion),
+			updateMockResponse(gofumptMockBinary.Binary, "", nil),
+			stagedBuildInfoMockResponse(gofumptMockBinary.Binary),
+			gobinariestest.GetModuleInfoMockResponse(shfmtMockBinary),
+			goclitest.GetVersionQueryMockResponse(shfmtMockBinary.Binary.ModuleURL, "v3.4.1", "v3.4.1"),
+			{Args: []string{"install", "-tags", "netgo", "mvdan.cc/sh/v3/cmd/shfmt@v3.4.1"}},
+			stagedBuildInfoMockResponse(shfmtMockBinary.Binary),
+		},
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := mockFilesystemUtils{}
+	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
+
+	err := UpdateBinaries(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, fsutils, &mockSmokeChecker{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, strings.TrimSpace(`
+Binary       Current version      Status
+gofumpt      v0.3.0               can upgrade to v0.4.1-0.20240105120000-0123456789ab (minor, requested master)
+shfmt        v3.4.2               can downgrade to v3.4.1 (requested v3.4.1)
+
+Upgrading gofumpt to v0.4.1-0.20240105120000-0123456789ab ... ✅
+
+Downgrading shfmt to v3.4.1 (build tags: netgo) ... ✅
+`), strings.TrimSpace(output.String()))
+}
+
+func TestDowngradeToVersionPinnedInConfig(t *testing.T) {
+	shfmtMockBinary := gobinariestest.GetShfmtMockBinary()
+	shfmtMockBinary.Binary.LatestVersion = "v3.3.1"
+
+	logger := zap.NewNop()
+	options := Options{
+		Config: config.Config{
+			Binaries: map[string]config.Binary{
+				"shfmt": {Version: "v3.3"},
+			},
+		},
+	}
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		Entries: []string{shfmtMockBinary.Binary.Name},
+	}
+	cmdRunner := goclitest.TestGoCmdRunner{
+		Responses: []goclitest.MockResponse{
+			gobinMockResponse(),
+			gobinariestest.GetModuleInfoMockResponse(shfmtMockBinary),
+			goclitest.GetVersionQueryMockResponse(shfmtMockBinary.Binary.ModuleURL, "v3.3", "v3.3.1"),
+			{Args: []string{"install", "mvdan.cc/sh/v3/cmd/shfmt@v3.3.1"}},
+			stagedBuildInfoMockResponse(shfmtMockBinary.Binary),
+		},
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := mockFilesystemUtils{}
+	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
+
+	err := UpdateBinaries(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, fsutils, &mockSmokeChecker{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, strings.TrimSpace(`
+Binary      Current version      Status
+shfmt       v3.4.2               can downgrade to v3.3.1 (requested v3.3)
+
+Downgrading shfmt to v3.3.1 ... ✅
+`), strings.TrimSpace(output.String()))
+}
+
+func TestParseBinaryArgs(t *testing.T) {
+	binaryNames, versionQueries, err := parseBinaryArgs([]string{"gopls@v0.14.2", "shfmt", "staticcheck@master"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"gopls", "shfmt", "staticcheck"}, binaryNames)
+	assert.Equal(t, map[string]string{"gopls": "v0.14.2", "staticcheck": "master"}, versionQueries)
+
+	_, _, err = parseBinaryArgs([]string{"gopls@"})
+	assert.EqualError(t, err, `missing the version after @ in "gopls@"`)
+
+	_, _, err = parseBinaryArgs([]string{"@v0.14.2"})
+	assert.EqualError(t, err, `missing the binary name in "@v0.14.2"`)
+}
+
+func TestNeverDowngradeUnlessAllowed(t *testing.T) {
+	shfmtMockBinary := gobinariestest.GetShfmtMockBinary()
+	shfmtMockBinary.Binary.LatestVersion = "v3.4.1"
+
+	testCases := []struct {
+		name             string
+		options          Options
+		installResponses []goclitest.MockResponse
+		expectedOutput   string
+	}{
+		{
+			name:    "default",
+			options: Options{},
+			expectedOutput: `
+Binary      Current version      Status
+shfmt       v3.4.2               newer than latest (latest: v3.4.1)
+`,
+		},
+		{
+			name:    "allow downgrade",
+			options: Options{AllowDowngrade: true},
+			installResponses: []goclitest.MockResponse{
+				{Args: []string{"install", "mvdan.cc/sh/v3/cmd/shfmt@v3.4.1"}},
+				stagedBuildInfoMockResponse(shfmtMockBinary.Binary),
+			},
+			expectedOutput: `
+Binary      Current version      Status
+shfmt       v3.4.2               newer than latest (latest: v3.4.1)
+
+Downgrading shfmt to v3.4.1 ... ✅
+`,
+		},
+		{
+			name:    "force reinstall",
+			options: Options{ForceReinstall: true},
+			installResponses: []goclitest.MockResponse{
+				{Args: []string{"install", "mvdan.cc/sh/v3/cmd/shfmt@v3.4.2"}},
+				stagedBuildInfoMockResponse(withLatestVersion(shfmtMockBinary.Binary, shfmtMockBinary.Binary.Version)),
+			},
+			expectedOutput: `
+Binary      Current version      Status
+shfmt       v3.4.2               newer than latest (latest: v3.4.1)
+
+Force-reinstalling shfmt v3.4.2 ... ✅
+`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			logger := zap.NewNop()
+			var output bytes.Buffer
+			lister := gobinariestest.TestSuccessDirectoryLister{
+				Entries: []string{shfmtMockBinary.Binary.Name},
+			}
+			cmdRunner := goclitest.TestGoCmdRunner{
+				Responses: []goclitest.MockResponse{
+					gobinMockResponse(),
+					gobinariestest.GetLatestVersionMockResponse(shfmtMockBinary.Binary),
+					gobinariestest.GetModuleInfoMockResponse(shfmtMockBinary),
+				},
+			}
+			cmdRunner.Responses = append(cmdRunner.Responses, testCase.installResponses...)
+
+			buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+			fsutils := mockFilesystemUtils{}
+			colorsFactory := colors.NewFactory(false)
+			reporter := NewTextReporter(&output, &colorsFactory, false)
+
+			err := UpdateBinaries(context.Background(), logger, testCase.options, &reporter, &cmdRunner, &lister, &buildInfoReader, fsutils, &mockSmokeChecker{})
+
+			assert.Nil(t, err)
+			assert.Equal(t, strings.TrimSpace(testCase.expectedOutput), strings.TrimSpace(output.String()))
+		})
+	}
+}
+
+func TestNewMajorVersion(t *testing.T) {
+	shfmtMockBinary := gobinariestest.GetShfmtMockBinary()
+
+	testCases := []struct {
+		name             string
+		options          Options
+		installResponses []goclitest.MockResponse
+		expectedOutput   string
+	}{
+		{
+			name:    "default",
+			options: Options{},
+			expectedOutput: `
+Binary      Current version      Status
+shfmt       v3.4.2               up-to-date (new major version available: v4.1.0)
+`,
+		},
+		{
+			name:    "allow major",
+			options: Options{AllowMajor: true},
+			installResponses: []goclitest.MockResponse{
+				{Args: []string{"install", "mvdan.cc/sh/v4/cmd/shfmt@v4.1.0"}},
+				stagedBuildInfoMockResponse(gobinaries.GoBinary{
+					PathURL:       "mvdan.cc/sh/v4/cmd/shfmt",
+					ModuleURL:     "mvdan.cc/sh/v4",
+					Path:          shfmtMockBinary.Binary.Path,
+					LatestVersion: "v4.1.0",
+				}),
+			},
+			expectedOutput: `
+Binary      Current version      Status
+shfmt       v3.4.2               can upgrade to v4.1.0 from mvdan.cc/sh/v4/cmd/shfmt
+
+Upgrading shfmt to v4.1.0 ... ✅
+`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			logger := zap.NewNop()
+			var output bytes.Buffer
+			lister := gobinariestest.TestSuccessDirectoryLister{
+				Entries: []string{shfmtMockBinary.Binary.Name},
+			}
+			cmdRunner := goclitest.TestGoCmdRunner{
+				Responses: []goclitest.MockResponse{
+					gobinMockResponse(),
+					gobinariestest.GetLatestVersionMockResponse(shfmtMockBinary.Binary),
+					goclitest.GetLatestVersionMockResponse("mvdan.cc/sh/v4", "v4.1.0"),
+					gobinariestest.GetModuleInfoMockResponse(shfmtMockBinary),
+				},
+			}
+			cmdRunner.Responses = append(cmdRunner.Responses, testCase.installResponses...)
+
+			buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+			fsutils := mockFilesystemUtils{}
+			colorsFactory := colors.NewFactory(false)
+			reporter := NewTextReporter(&output, &colorsFactory, false)
+
+			err := UpdateBinaries(context.Background(), logger, testCase.options, &reporter, &cmdRunner, &lister, &buildInfoReader, fsutils, &mockSmokeChecker{})
+
+			assert.Nil(t, err)
+			assert.Equal(t, strings.TrimSpace(testCase.expectedOutput), strings.TrimSpace(output.String()))
+		})
+	}
+}
+
+// blockingGoCmdRunner blocks installing the first binary until the second
+// binary starts being installed.
+type blockingGoCmdRunner struct {
+	goclitest.TestGoCmdRunner
+	firstInstallArg  string
+	secondInstallArg string
+	secondStarted    chan struct{}
+}
+
+func (r *blockingGoCmdRunner) RunGoCommandWithOptions(ctx context.Context, options gocli.RunOptions, args ...string) (string, error) {
+	if args[0] != "install" {
+		return r.TestGoCmdRunner.RunGoCommandWithOptions(ctx, options, args...)
+	}
+
+	switch args[len(args)-1] {
+	case r.firstInstallArg:
+		<-r.secondStarted
+	case r.secondInstallArg:
+		close(r.secondStarted)
+	}
+
+	return r.TestGoCmdRunner.RunGoCommandWithOptions(ctx, options, args...)
+}
+
+func TestInstallConcurrentlyWithOrderedOutput(t *testing.T) {
+	gofumptMockBinary := gobinariestest.GetGofumptMockBinary()
+	gofumptMockBinary.Binary.LatestVersion = "v0.4.0"
+	shfmtMockBinary := gobinariestest.GetShfmtMockBinary()
+	shfmtMockBinary.Binary.LatestVersion = "v3.4.3"
+
+	logger := zap.NewNop()
+	options := Options{Jobs: 2}
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		Entries: []string{gofumptMockBinary.Binary.Name, shfmtMockBinary.Binary.Name},
+	}
+	cmdRunner := blockingGoCmdRunner{
+		TestGoCmdRunner: goclitest.TestGoCmdRunner{
+			Responses: []goclitest.MockResponse{
+				gobinMockResponse(),
+				gobinariestest.GetLatestVersionMockResponse(gofumptMockBinary.Binary),
+				gobinariestest.GetModuleInfoMockResponse(gofumptMockBinary),
+				updateMockResponse(gofumptMockBinary.Binary, "", nil),
+				stagedBuildInfoMockResponse(gofumptMockBinary.Binary),
+				gobinariestest.GetLatestVersionMockResponse(shfmtMockBinary.Binary),
+				gobinariestest.GetModuleInfoMockResponse(shfmtMockBinary),
+				updateMockResponse(shfmtMockBinary.Binary, "", errors.New("exit status 1")),
+			},
+		},
+		firstInstallArg:  fmt.Sprintf("%s@%s", gofumptMockBinary.Binary.PathURL, gofumptMockBinary.Binary.LatestVersion),
+		secondInstallArg: fmt.Sprintf("%s@%s", shfmtMockBinary.Binary.PathURL, shfmtMockBinary.Binary.LatestVersion),
+		secondStarted:    make(chan struct{}),
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := mockFilesystemUtils{}
+	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
+
+	err := UpdateBinaries(context.Background(), logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, fsutils, &mockSmokeChecker{})
+
+	assert.EqualError(t, err, "could not install 1 package(s)")
+	assert.Equal(t, strings.TrimSpace(`
+Binary       Current version      Status
+gofumpt      v0.3.0               can upgrade to v0.4.0 (minor)
+shfmt        v3.4.2               can upgrade to v3.4.3 (patch)
+
+Upgrading gofumpt to v0.4.0 ... ✅
+
+Upgrading shfmt to v3.4.3 ... ❌
+    Could not install package: exit status 1
+
+Could not install 1 package(s)
+`), strings.TrimSpace(output.String()))
+}
+
+// cancelingGoCmdRunner cancels the run once the binary is installed, as if the
+// user pressed Ctrl-C.
+type cancelingGoCmdRunner struct {
+	goclitest.TestGoCmdRunner
+	installArg string
+	cancel     context.CancelFunc
+}
+
+func (r *cancelingGoCmdRunner) RunGoCommandWithOptions(ctx context.Context, options gocli.RunOptions, args ...string) (string, error) {
+	output, err := r.TestGoCmdRunner.RunGoCommandWithOptions(ctx, options, args...)
+	if args[0] == "install" && args[len(args)-1] == r.installArg {
+		r.cancel()
+	}
+
+	return output, err
+}
+
+func TestStopInstallingWhenInterrupted(t *testing.T) {
+	gofumptMockBinary := gobinariestest.GetGofumptMockBinary()
+	gofumptMockBinary.Binary.LatestVersion = "v0.4.0"
+	shfmtMockBinary := gobinariestest.GetShfmtMockBinary()
+	shfmtMockBinary.Binary.LatestVersion = "v3.4.3"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger := zap.NewNop()
+	options := Options{Jobs: 1}
+	var output bytes.Buffer
+	lister := gobinariestest.TestSuccessDirectoryLister{
+		Entries: []string{gofumptMockBinary.Binary.Name, shfmtMockBinary.Binary.Name},
+	}
+	cmdRunner := cancelingGoCmdRunner{
+		TestGoCmdRunner: goclitest.TestGoCmdRunner{
+			Responses: []goclitest.MockResponse{
+				gobinMockResponse(),
+				gobinariestest.GetLatestVersionMockResponse(gofumptMockBinary.Binary),
+				gobinariestest.GetModuleInfoMockResponse(gofumptMockBinary),
+				updateMockResponse(gofumptMockBinary.Binary, "", nil),
+				stagedBuildInfoMockResponse(gofumptMockBinary.Binary),
+				gobinariestest.GetLatestVersionMockResponse(shfmtMockBinary.Binary),
+				gobinariestest.GetModuleInfoMockResponse(shfmtMockBinary),
+			},
+		},
+		installArg: fmt.Sprintf("%s@%s", gofumptMockBinary.Binary.PathURL, gofumptMockBinary.Binary.LatestVersion),
+		cancel:     cancel,
+	}
+
+	buildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&cmdRunner)
+	fsutils := mockFilesystemUtils{}
+	colorsFactory := colors.NewFactory(false)
+	reporter := NewTextReporter(&output, &colorsFactory, false)
+
+	err := UpdateBinaries(ctx, logger, options, &reporter, &cmdRunner, &lister, &buildInfoReader, fsutils, &mockSmokeChecker{})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.EqualError(t, err, "the run was interrupted: context canceled")
+	assert.Equal(t, strings.TrimSpace(`
+Binary       Current version      Status
+gofumpt      v0.3.0               can upgrade to v0.4.0 (minor)
+shfmt        v3.4.2               can upgrade to v3.4.3 (patch)
+
+Upgrading gofumpt to v0.4.0 ... ✅
+
+Interrupted: 1 upgraded, 1 not started
+`), strings.TrimSpace(output.String()))
+}
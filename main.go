@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/Gelio/go-global-update/internal/backup"
 	"github.com/Gelio/go-global-update/internal/colors"
+	"github.com/Gelio/go-global-update/internal/config"
 	"github.com/Gelio/go-global-update/internal/gobinaries"
 	"github.com/Gelio/go-global-update/internal/gocli"
 	"github.com/Gelio/go-global-update/internal/updater"
@@ -37,9 +43,14 @@ func main() {
    Examples:
 
    * go-global-update gofumpt gopls shfmt
-   * go-global-update --dry-run`,
+   * go-global-update --dry-run
+   * go-global-update export tools.json
+   * go-global-update import tools.json
+   * go-global-update sync --prune tools.json
+   * go-global-update rollback gopls
+   * go-global-update path-audit`,
 		Version:                "v0.2.5",
-		ArgsUsage:              "[binaries to update...]",
+		ArgsUsage:              "[binaries to update (name or name@version)...]",
 		UseShortOptionHandling: true,
 		Flags: []cli.Flag{
 			&cli.BoolFlag{
@@ -65,6 +76,68 @@ func main() {
 				Aliases: []string{"f"},
 				Usage:   "Force reinstall all binaries, even if they do not need to be updated",
 			},
+			&cli.BoolFlag{
+				Name:  "allow-downgrade",
+				Usage: "Downgrade binaries that are newer than the latest version (for example prereleases or pseudo-versions)",
+			},
+			&cli.BoolFlag{
+				Name:  "allow-major",
+				Usage: "Upgrade binaries to new major versions of their modules (for example from mvdan.cc/sh/v3 to mvdan.cc/sh/v4)",
+			},
+			&cli.BoolFlag{
+				Name:  "install-latest",
+				Usage: "Install @latest (or the version query from the config file) instead of the exact version shown in the summary",
+			},
+			&cli.IntFlag{
+				Name:        "jobs",
+				Aliases:     []string{"j"},
+				Usage:       "Maximum number of binaries installed concurrently",
+				DefaultText: "number of CPUs",
+			},
+			&cli.BoolFlag{
+				Name:  "ignore-non-go",
+				Usage: "Hide entries in GOBIN that are not go binaries (for example shell scripts or directories)",
+			},
+			&cli.StringFlag{
+				Name:  "symlinks",
+				Usage: "How to update binaries that are symlinks. One of: target (update the file the symlink points to), skip, retarget (install a new versioned file and point the symlink to it)",
+				Value: string(updater.SymlinkModeTarget),
+			},
+			&cli.StringSliceFlag{
+				Name:  "dir",
+				Usage: "Additional directory with go binaries, besides GOBIN and GOPATH. Can be repeated",
+			},
+			&cli.IntFlag{
+				Name:  "backups",
+				Usage: "Number of backups of each binary to keep. Binaries are backed up before they are updated. Use 0 to disable backups",
+				Value: 3,
+			},
+			&cli.StringFlag{
+				Name:  "backup-dir",
+				Usage: "Directory with backups of binaries. Defaults to $XDG_DATA_HOME/go-global-update/backups",
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Output format. One of: text, json",
+				Value:   string(updater.OutputFormatText),
+			},
+			&cli.StringFlag{
+				Name:  "config",
+				Usage: "Path to the config file (.toml or .yaml). Defaults to $XDG_CONFIG_HOME/go-global-update/config.(toml|yaml)",
+			},
+			&cli.DurationFlag{
+				Name:  "timeout",
+				Usage: "Stop the whole run after this long (for example 10m). Binaries that are being installed are interrupted",
+			},
+			&cli.DurationFlag{
+				Name:  "command-timeout",
+				Usage: "Interrupt a single go command (for example \"go install\") after this long",
+			},
+			&cli.BoolFlag{
+				Name:  "go-version-m",
+				Usage: "Introspect binaries using the \"go version -m\" command instead of reading their build information directly",
+			},
 		},
 		Action: func(c *cli.Context) error {
 			forceColors := c.Bool("colors")
@@ -76,30 +149,73 @@ func main() {
 			}
 			defer logger.Sync()
 
-			cmdRunner := gocli.NewCmdRunner(logger)
+			cmdRunner := newCmdRunner(c, logger)
+
+			outputFormat, err := updater.ParseOutputFormat(c.String("output"))
+			if err != nil {
+				return err
+			}
+
+			symlinkMode, err := updater.ParseSymlinkMode(c.String("symlinks"))
+			if err != nil {
+				return err
+			}
+
+			config, err := loadConfig(c.String("config"))
+			if err != nil {
+				return err
+			}
+
+			backups, err := newBackupStore(c)
+			if err != nil {
+				return err
+			}
 
 			options := updater.Options{
 				DryRun:           c.Bool("dry-run"),
-				Verbose:          c.Bool("verbose"),
 				ForceReinstall:   c.Bool("force"),
+				AllowDowngrade:   c.Bool("allow-downgrade"),
+				AllowMajor:       c.Bool("allow-major"),
+				InstallLatest:    c.Bool("install-latest"),
+				Jobs:             c.Int("jobs"),
 				BinariesToUpdate: c.Args().Slice(),
+				Config:           config,
+				Dirs:             binaryDirs(c, config),
+				Backups:          backups,
+				IgnoreNonGo:      c.Bool("ignore-non-go"),
+				Symlinks:         symlinkMode,
 			}
 
 			if options.DryRun && options.ForceReinstall {
 				return fmt.Errorf("--dry-run and --force options cannot be used together")
 			}
 
+			buildInfoReader := newBuildInfoReader(c, &cmdRunner)
+			reporter := updater.NewReporter(outputFormat, os.Stdout, &colorsDecoratorFactory, c.Bool("verbose"))
+
+			ctx, cancel := newRunContext(c)
+			defer cancel()
+
 			err = updater.UpdateBinaries(
+				ctx,
 				logger,
 				options,
-				os.Stdout,
-				&colorsDecoratorFactory,
+				reporter,
 				&cmdRunner,
 				&gobinaries.FilesystemDirectoryLister{},
+				buildInfoReader,
 				&updater.Filesystem{},
+				&updater.ExecSmokeChecker{},
 			)
 			return err
 		},
+		Commands: []*cli.Command{
+			newExportCommand(&loggerConfig),
+			newImportCommand(&loggerConfig),
+			newSyncCommand(&loggerConfig),
+			newRollbackCommand(&loggerConfig),
+			newPathAuditCommand(&loggerConfig),
+		},
 		Before: func(c *cli.Context) error {
 			debugMode := c.Bool("debug")
 			updateLoggerLevel(&loggerConfig, debugMode)
@@ -110,11 +226,85 @@ func main() {
 		},
 	}
 
-	if err := app.Run(os.Args); err != nil {
+	// NOTE: the first Ctrl-C stops the run gracefully. Restoring the default
+	// behavior afterwards lets the second Ctrl-C terminate the process.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if err := app.RunContext(ctx, os.Args); err != nil {
+		// NOTE: failed installs are already reported, so only the exit code
+		// needs to be set.
+		var failedErr *updater.FailedError
+		if errors.As(err, &failedErr) {
+			os.Exit(1)
+		}
+
 		log.Fatalf("could not run command: %v", err)
 	}
 }
 
+// binaryDirs returns the additional directories with go binaries from the
+// --dir flags and the config file.
+func binaryDirs(c *cli.Context, config config.Config) []string {
+	return append(c.StringSlice("dir"), config.Dirs...)
+}
+
+// newRunContext returns the context of the run, limited by the --timeout flag.
+func newRunContext(c *cli.Context) (context.Context, context.CancelFunc) {
+	if timeout := c.Duration("timeout"); timeout > 0 {
+		return context.WithTimeout(c.Context, timeout)
+	}
+
+	return context.WithCancel(c.Context)
+}
+
+func newCmdRunner(c *cli.Context, logger *zap.Logger) gocli.RealGoCmdRunner {
+	return gocli.NewCmdRunner(logger, c.Duration("command-timeout"))
+}
+
+func newBuildInfoReader(c *cli.Context, cmdRunner gocli.GoCmdRunner) gobinaries.BuildInfoReader {
+	if c.Bool("go-version-m") {
+		// NOTE: GOBIN is not known yet, so the binaries are read outside of any
+		// module or workspace, but not from GOBIN.
+		hermeticCmdRunner := gocli.NewHermeticCmdRunner(cmdRunner, "")
+		goVersionBuildInfoReader := gobinaries.NewGoVersionBuildInfoReader(&hermeticCmdRunner)
+		return &goVersionBuildInfoReader
+	}
+
+	return &gobinaries.FileBuildInfoReader{}
+}
+
+// newBackupStore returns nil if backups are disabled.
+func newBackupStore(c *cli.Context) (*backup.Store, error) {
+	generations := c.Int("backups")
+	if generations <= 0 {
+		return nil, nil
+	}
+
+	dir := c.String("backup-dir")
+	if dir == "" {
+		var err error
+		dir, err = backup.DefaultDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	store := backup.NewStore(dir, generations)
+	return &store, nil
+}
+
+func loadConfig(path string) (config.Config, error) {
+	if path != "" {
+		return config.Load(path)
+	}
+
+	return config.LoadDefault()
+}
+
 func updateLoggerLevel(loggerConfig *zap.Config, debugMode bool) {
 	logLevel := zap.InfoLevel
 	if debugMode {
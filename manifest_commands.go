@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Gelio/go-global-update/internal/colors"
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+	"github.com/Gelio/go-global-update/internal/manifest"
+	"github.com/Gelio/go-global-update/internal/updater"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+)
+
+func newExportCommand(loggerConfig *zap.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "export",
+		Usage: "Write a manifest of binaries installed in GOBIN, GOPATH, and --dir directories",
+		Description: `The manifest contains the package path, module, version, and build tags of
+   each binary. Use the "import" command to install the same binaries on
+   another machine.
+
+   The manifest is written to stdout unless a file is provided.`,
+		ArgsUsage: "[manifest file]",
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:  "binary",
+				Usage: "Only export the given binary. Can be used multiple times",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() > 1 {
+				return fmt.Errorf("expected at most one manifest file, got %d arguments", c.NArg())
+			}
+
+			logger, err := loggerConfig.Build()
+			if err != nil {
+				return fmt.Errorf("cannot initialize zap logger: %w", err)
+			}
+			defer logger.Sync()
+
+			config, err := loadConfig(c.String("config"))
+			if err != nil {
+				return err
+			}
+
+			var out io.Writer = os.Stdout
+			if path := c.Args().First(); path != "" && path != "-" {
+				file, err := os.Create(path)
+				if err != nil {
+					return fmt.Errorf("could not create manifest file: %w", err)
+				}
+				defer file.Close()
+				out = file
+			}
+
+			cmdRunner := newCmdRunner(c, logger)
+			ctx, cancel := newRunContext(c)
+			defer cancel()
+
+			return updater.ExportManifest(
+				ctx,
+				logger,
+				c.StringSlice("binary"),
+				binaryDirs(c, config),
+				out,
+				&cmdRunner,
+				&gobinaries.FilesystemDirectoryLister{},
+				newBuildInfoReader(c, &cmdRunner),
+				&updater.Filesystem{},
+			)
+		},
+	}
+}
+
+func newImportCommand(loggerConfig *zap.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "import",
+		Usage: "Install binaries from a manifest written by the \"export\" command",
+		Description: `Runs "go install path@version" for every binary in the manifest.
+
+   Use "-" to read the manifest from stdin.`,
+		ArgsUsage: "<manifest file>",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "latest",
+				Usage: "Install the latest versions instead of the versions from the manifest",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("expected exactly one manifest file, got %d arguments", c.NArg())
+			}
+
+			logger, err := loggerConfig.Build()
+			if err != nil {
+				return fmt.Errorf("cannot initialize zap logger: %w", err)
+			}
+			defer logger.Sync()
+
+			outputFormat, err := updater.ParseOutputFormat(c.String("output"))
+			if err != nil {
+				return err
+			}
+
+			config, err := loadConfig(c.String("config"))
+			if err != nil {
+				return err
+			}
+
+			importedManifest, err := readManifest(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			colorsDecoratorFactory := colors.NewFactory(c.Bool("colors"))
+			reporter := updater.NewReporter(outputFormat, os.Stdout, &colorsDecoratorFactory, c.Bool("verbose"))
+			cmdRunner := newCmdRunner(c, logger)
+			ctx, cancel := newRunContext(c)
+			defer cancel()
+
+			return updater.ImportManifest(
+				ctx,
+				logger,
+				importedManifest,
+				updater.ImportOptions{
+					Latest: c.Bool("latest"),
+					Config: config,
+				},
+				reporter,
+				&cmdRunner,
+				newBuildInfoReader(c, &cmdRunner),
+				&updater.Filesystem{},
+			)
+		},
+	}
+}
+
+func newSyncCommand(loggerConfig *zap.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "sync",
+		Usage: "Make the binaries in GOBIN match a manifest written by the \"export\" command",
+		Description: `Installs missing binaries and upgrades or downgrades other binaries to the
+   versions from the manifest. The plan is printed before any binary is
+   changed. Use the global --dry-run flag to only print the plan.
+
+   Use "-" to read the manifest from stdin.
+
+   Examples:
+
+   * go-global-update sync --prune tools.json
+   * go-global-update --dry-run sync tools.json`,
+		ArgsUsage: "<manifest file>",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "prune",
+				Usage: "Remove binaries that are not listed in the manifest",
+			},
+		},
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 1 {
+				return fmt.Errorf("expected exactly one manifest file, got %d arguments", c.NArg())
+			}
+
+			logger, err := loggerConfig.Build()
+			if err != nil {
+				return fmt.Errorf("cannot initialize zap logger: %w", err)
+			}
+			defer logger.Sync()
+
+			outputFormat, err := updater.ParseOutputFormat(c.String("output"))
+			if err != nil {
+				return err
+			}
+
+			config, err := loadConfig(c.String("config"))
+			if err != nil {
+				return err
+			}
+
+			symlinkMode, err := updater.ParseSymlinkMode(c.String("symlinks"))
+			if err != nil {
+				return err
+			}
+
+			syncedManifest, err := readManifest(c.Args().First())
+			if err != nil {
+				return err
+			}
+
+			colorsDecoratorFactory := colors.NewFactory(c.Bool("colors"))
+			reporter := updater.NewReporter(outputFormat, os.Stdout, &colorsDecoratorFactory, c.Bool("verbose"))
+			cmdRunner := newCmdRunner(c, logger)
+			ctx, cancel := newRunContext(c)
+			defer cancel()
+
+			return updater.SyncManifest(
+				ctx,
+				logger,
+				syncedManifest,
+				updater.SyncOptions{
+					DryRun:      c.Bool("dry-run"),
+					Prune:       c.Bool("prune"),
+					Config:      config,
+					Dirs:        binaryDirs(c, config),
+					IgnoreNonGo: c.Bool("ignore-non-go"),
+					Symlinks:    symlinkMode,
+				},
+				reporter,
+				&cmdRunner,
+				&gobinaries.FilesystemDirectoryLister{},
+				newBuildInfoReader(c, &cmdRunner),
+				&updater.Filesystem{},
+			)
+		},
+	}
+}
+
+func readManifest(path string) (manifest.Manifest, error) {
+	if path == "-" {
+		return manifest.Read(os.Stdin)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return manifest.Manifest{}, fmt.Errorf("could not open manifest file: %w", err)
+	}
+	defer file.Close()
+
+	return manifest.Read(file)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Gelio/go-global-update/internal/colors"
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+	"github.com/Gelio/go-global-update/internal/updater"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+)
+
+func newPathAuditCommand(loggerConfig *zap.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "path-audit",
+		Usage: "Find go binaries in PATH that are shadowed, outdated, or not reachable",
+		Description: `Walks every directory in PATH and reports:
+
+   * binaries from GOBIN that are shadowed by a different executable with the
+     same name earlier in PATH,
+   * go binaries outside of GOBIN that are outdated,
+   * binaries from GOBIN that are not reachable via PATH at all.
+
+   Nothing is changed. The command fails if any problem is found.
+
+   Examples:
+
+   * go-global-update path-audit
+   * go-global-update --output json path-audit`,
+		Action: func(c *cli.Context) error {
+			if c.NArg() != 0 {
+				return fmt.Errorf("expected no arguments, got %d", c.NArg())
+			}
+
+			logger, err := loggerConfig.Build()
+			if err != nil {
+				return fmt.Errorf("cannot initialize zap logger: %w", err)
+			}
+			defer logger.Sync()
+
+			outputFormat, err := updater.ParseOutputFormat(c.String("output"))
+			if err != nil {
+				return err
+			}
+
+			config, err := loadConfig(c.String("config"))
+			if err != nil {
+				return err
+			}
+
+			colorsDecoratorFactory := colors.NewFactory(c.Bool("colors"))
+			reporter := updater.NewReporter(outputFormat, os.Stdout, &colorsDecoratorFactory, c.Bool("verbose"))
+			cmdRunner := newCmdRunner(c, logger)
+			ctx, cancel := newRunContext(c)
+			defer cancel()
+
+			return updater.AuditPath(
+				ctx,
+				logger,
+				updater.PathAuditOptions{
+					PATH:   os.Getenv("PATH"),
+					Config: config,
+					Dirs:   binaryDirs(c, config),
+				},
+				reporter,
+				&cmdRunner,
+				&gobinaries.FilesystemDirectoryLister{},
+				newBuildInfoReader(c, &cmdRunner),
+				&updater.Filesystem{},
+			)
+		},
+	}
+}
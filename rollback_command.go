@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/Gelio/go-global-update/internal/gobinaries"
+	"github.com/Gelio/go-global-update/internal/updater"
+	"github.com/urfave/cli/v2"
+	"go.uber.org/zap"
+)
+
+func newRollbackCommand(loggerConfig *zap.Config) *cli.Command {
+	return &cli.Command{
+		Name:  "rollback",
+		Usage: "Restore a backup of a binary made before it was updated",
+		Description: `Restores the newest backup of the binary, or the backup of the given
+   version.
+
+   Examples:
+
+   * go-global-update rollback gopls
+   * go-global-update rollback gopls v0.14.2`,
+		ArgsUsage: "<binary> [version]",
+		Action: func(c *cli.Context) error {
+			if c.NArg() < 1 || c.NArg() > 2 {
+				return fmt.Errorf("expected a binary name and an optional version, got %d arguments", c.NArg())
+			}
+
+			logger, err := loggerConfig.Build()
+			if err != nil {
+				return fmt.Errorf("cannot initialize zap logger: %w", err)
+			}
+			defer logger.Sync()
+
+			config, err := loadConfig(c.String("config"))
+			if err != nil {
+				return err
+			}
+
+			backups, err := newBackupStore(c)
+			if err != nil {
+				return err
+			}
+			if backups == nil {
+				return fmt.Errorf("backups are disabled (--backups is 0)")
+			}
+
+			cmdRunner := newCmdRunner(c, logger)
+			ctx, cancel := newRunContext(c)
+			defer cancel()
+
+			entry, err := updater.RollbackBinary(
+				ctx,
+				logger,
+				c.Args().Get(0),
+				c.Args().Get(1),
+				binaryDirs(c, config),
+				backups,
+				&cmdRunner,
+				&gobinaries.FilesystemDirectoryLister{},
+				&updater.Filesystem{},
+			)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Rolled back %s to %s (backed up on %s)\n", entry.Name, entry.Version, entry.Time.Format("2006-01-02 15:04:05"))
+			return nil
+		},
+	}
+}
@@ -17,8 +17,25 @@ const INTEGRATION_TESTS_DIR string = "integration-tests"
 
 // NOTE: the working directory in the tests is the directory of the current
 // test file.
-// main.go is in the parent directory.
-var mainGoBinaryPath string = filepath.Join("..", "main.go")
+// The main package is in the parent directory.
+var mainPackagePath string = ".."
+
+// mainGoFiles returns the source files of the main package. Building them
+// directly (like `go build main.go`) produces a binary with the
+// `command-line-arguments` path.
+func mainGoFiles(t *testing.T) []string {
+	files, err := filepath.Glob(filepath.Join(mainPackagePath, "*.go"))
+	require.Nil(t, err, "could not list the source files of the main package")
+
+	var sourceFiles []string
+	for _, file := range files {
+		if !strings.HasSuffix(file, "_test.go") {
+			sourceFiles = append(sourceFiles, file)
+		}
+	}
+
+	return sourceFiles
+}
 
 // prepareTempoGobin returns an absolute path to a temporary directory. It
 // could serve as GOBIN for a test.
@@ -46,7 +63,7 @@ func newTestCommand(t *testing.T, gobin, name string, args ...string) *exec.Cmd
 }
 
 func newGoGlobalUpdateCommand(t *testing.T, gobin string, args ...string) *exec.Cmd {
-	args = append([]string{"run", mainGoBinaryPath}, args...)
+	args = append([]string{"run", mainPackagePath}, args...)
 	return newTestCommand(t, gobin, "go", args...)
 }
 
@@ -206,8 +223,9 @@ func TestBuiltFromSourceCommandLineArguments(t *testing.T) {
 	defer os.RemoveAll(gobin)
 
 	builtBinaryName := binaryName("built-binary")
-	output, err := newTestCommand(t, gobin, "go", "build", "-o", filepath.Join(gobin, builtBinaryName), mainGoBinaryPath).CombinedOutput()
-	require.Nilf(t, err, "could not build %s\noutput: %s", mainGoBinaryPath, string(output))
+	buildArgs := append([]string{"build", "-o", filepath.Join(gobin, builtBinaryName)}, mainGoFiles(t)...)
+	output, err := newTestCommand(t, gobin, "go", buildArgs...).CombinedOutput()
+	require.Nilf(t, err, "could not build %s\noutput: %s", mainPackagePath, string(output))
 
 	output, err = newGoGlobalUpdateCommand(t, gobin, builtBinaryName).CombinedOutput()
 	assert.Nilf(t, err, "could not run go-global-update for %s\noutput: %s", builtBinaryName, string(output))
@@ -318,3 +336,36 @@ func TestPreserveBuildTags(t *testing.T) {
 	assert.Contains(t, output, "-tags=tagA,tagB", "expected build tags not found after updating")
 	assert.NotContains(t, output, "v3.4.2", "binary was not updated")
 }
+
+// TestReportFailuresOnce makes sure the number of failed installs is only
+// printed once.
+func TestReportFailuresOnce(t *testing.T) {
+	ensureIntegrationTestsDirExists(t)
+	gobin := prepareTempGobin(t)
+	defer os.RemoveAll(gobin)
+
+	manifestPath := filepath.Join(gobin, "tools.json")
+	require.Nil(t, os.WriteFile(manifestPath, []byte(`{"binaries": [{
+		"name": "missing",
+		"path": "example.com/missing/cmd/missing",
+		"module": "example.com/missing",
+		"version": "v1.0.0"
+	}]}`), 0o644))
+
+	command := newGoGlobalUpdateCommand(t, gobin, "import", manifestPath)
+	// NOTE: the module cannot be downloaded, so installing it always fails.
+	command.Env = append(command.Env, "GOPROXY=off")
+	var stderr strings.Builder
+	command.Stderr = &stderr
+	output, err := command.Output()
+
+	assert.NotNil(t, err, "expected the import to fail")
+	assert.Equal(t, strings.TrimSpace(`
+Installing missing v1.0.0 ... ❌
+    Could not install package
+go: example.com/missing/cmd/missing@v1.0.0: module lookup disabled by GOPROXY=off
+
+Could not install 1 package(s)
+`), strings.TrimSpace(string(output)))
+	assert.NotContains(t, stderr.String(), "could not install", "the failures should not be printed again")
+}